@@ -1,15 +1,23 @@
 package inputparser
 
 import (
+	"bytes"
 	"encoding/csv"
+	"errors"
+	"io"
 	"os"
+	"strings"
 
 	"github.com/molinama/timescale/src/model"
 )
 
+// header is the optional first record of a query parameters CSV input.
+var header = []string{"hostname", "start_time", "end_time"}
+
 type CSVReader struct {
-	reader *csv.Reader
-	file   *os.File
+	reader      *csv.Reader
+	closer      io.Closer
+	checkHeader bool
 }
 
 func NewCSVReader(csvFilePath string) (Reader, error) {
@@ -18,20 +26,113 @@ func NewCSVReader(csvFilePath string) (Reader, error) {
 		return nil, err
 	}
 
+	return newCSVReader(file, file), nil
+}
+
+// NewCSVStreamReader returns a Reader over an already opened stream such as
+// os.Stdin. Closing the Reader does not close the underlying stream.
+func NewCSVStreamReader(stream io.Reader) Reader {
+	return newCSVReader(stream, io.NopCloser(stream))
+}
+
+func newCSVReader(stream io.Reader, closer io.Closer) *CSVReader {
+	reader := csv.NewReader(stream)
+	// Records with a wrong number of fields are reported by the validation.
+	reader.FieldsPerRecord = -1
+
 	return &CSVReader{
-		file:   file,
-		reader: csv.NewReader(file),
-	}, nil
+		reader:      reader,
+		closer:      closer,
+		checkHeader: true,
+	}
+}
 
+// CountRows returns the number of rows of the CSV file after the header, if
+// any. The lines are only counted, not parsed, so the invalid rows are included.
+func CountRows(csvFilePath string) (int, error) {
+	file, err := os.Open(csvFilePath)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	buffer := make([]byte, 64*1024)
+	rows := 0
+	first, last := true, byte('\n')
+	for {
+		n, err := file.Read(buffer)
+		if n > 0 {
+			if first && bytes.HasPrefix(buffer[:n], []byte(strings.Join(header, ","))) {
+				rows--
+			}
+			first = false
+			rows += bytes.Count(buffer[:n], []byte{'\n'})
+			last = buffer[n-1]
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	// The last row may not end with a newline.
+	if last != '\n' {
+		rows++
+	}
+	return max(rows, 0), nil
 }
+
 func (r *CSVReader) Parse() (*model.QueryParams, error) {
-	data, err := r.reader.Read()
+	data, err := r.read()
 	if err != nil {
 		return nil, err
 	}
-	return model.NewQueryParams(data)
+
+	params, err := model.NewQueryParams(data)
+	if err != nil {
+		return nil, r.rowError(err)
+	}
+	return params, nil
 }
 
 func (r *CSVReader) Close() error {
-	return r.file.Close()
+	return r.closer.Close()
+}
+
+// read returns the next record, skipping the header if it is the first one.
+func (r *CSVReader) read() ([]string, error) {
+	data, err := r.reader.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, &RowError{Line: parseErr.Line, Err: parseErr.Err}
+		}
+		return nil, err
+	}
+
+	if r.checkHeader {
+		r.checkHeader = false
+		if isHeader(data) {
+			return r.read()
+		}
+	}
+	return data, nil
+}
+
+func (r *CSVReader) rowError(err error) error {
+	line, _ := r.reader.FieldPos(0)
+	return &RowError{Line: line, Err: err}
+}
+
+func isHeader(data []string) bool {
+	if len(data) != len(header) {
+		return false
+	}
+	for i := range data {
+		if !strings.EqualFold(strings.TrimSpace(data[i]), header[i]) {
+			return false
+		}
+	}
+	return true
 }
@@ -0,0 +1,127 @@
+package inputparser
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/molinama/timescale/src/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVStreamReader_Parse(t *testing.T) {
+	tests := []struct {
+		name        string
+		input       string
+		want        []*model.QueryParams
+		wantErrLine []int
+	}{
+		{
+			name: "With Header",
+			input: `hostname,start_time,end_time
+host_000008,2017-01-01 08:59:22,2017-01-01 09:59:22`,
+			want: []*model.QueryParams{
+				{Hostname: "host_000008", StartTime: "2017-01-01 08:59:22", EndTime: "2017-01-01 09:59:22"},
+			},
+		},
+		{
+			name:  "Without Header",
+			input: "host_000001,2017-01-02 13:02:02,2017-01-02 14:02:02\n",
+			want: []*model.QueryParams{
+				{Hostname: "host_000001", StartTime: "2017-01-02 13:02:02", EndTime: "2017-01-02 14:02:02"},
+			},
+		},
+		{
+			name: "Invalid Rows",
+			input: `hostname,start_time,end_time
+host_000008,2017-01-01
+host_000001,2017-01-02 13:02:02,2017-01-02 14:02:02
+,2017-01-02 13:02:02,2017-01-02 14:02:02`,
+			want: []*model.QueryParams{
+				{Hostname: "host_000001", StartTime: "2017-01-02 13:02:02", EndTime: "2017-01-02 14:02:02"},
+			},
+			wantErrLine: []int{2, 4},
+		},
+		{
+			name:  "Empty Input",
+			input: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reader := NewCSVStreamReader(strings.NewReader(tt.input))
+			defer reader.Close()
+
+			var got []*model.QueryParams
+			var gotErrLine []int
+			for {
+				params, err := reader.Parse()
+				if err == io.EOF {
+					break
+				}
+				var rowErr *RowError
+				if errors.As(err, &rowErr) {
+					gotErrLine = append(gotErrLine, rowErr.Line)
+					continue
+				}
+				if !assert.NoError(t, err) {
+					return
+				}
+				got = append(got, params)
+			}
+			assert.Equal(t, tt.want, got)
+			assert.Equal(t, tt.wantErrLine, gotErrLine)
+		})
+	}
+}
+
+func TestCountRows(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    int
+	}{
+		{
+			name: "Header and invalid row",
+			content: `hostname,start_time,end_time
+host_000008,2017-01-01 08:59:22,2017-01-01 09:59:22
+host_000008,2017-01-01
+host_000001,2017-01-02 13:02:02,2017-01-02 14:02:02
+`,
+			want: 3,
+		},
+		{
+			name:    "No header nor final newline",
+			content: "host_000008,2017-01-01 08:59:22,2017-01-01 09:59:22\nhost_000001,2017-01-02 13:02:02,2017-01-02 14:02:02",
+			want:    2,
+		},
+		{
+			name:    "Header only",
+			content: "hostname,start_time,end_time",
+			want:    0,
+		},
+		{
+			name: "Empty",
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "query_params.csv")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatalf("Error writing temp CSV file: %v", err)
+			}
+
+			rows, err := CountRows(path)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, rows)
+		})
+	}
+
+	_, err := CountRows(filepath.Join(t.TempDir(), "missing.csv"))
+	assert.Error(t, err)
+}
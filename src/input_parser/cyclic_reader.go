@@ -0,0 +1,57 @@
+package inputparser
+
+import (
+	"io"
+	"math/rand"
+
+	"github.com/molinama/timescale/src/model"
+)
+
+// CyclicReader reads the input of another Reader and then replays it over and
+// over. The parameters are kept in memory after the first pass, so any input,
+// including standard input, can be replayed.
+type CyclicReader struct {
+	reader  Reader
+	shuffle bool
+	params  []*model.QueryParams
+	replay  bool
+	next    int
+}
+
+// NewCyclicReader returns a Reader that never reaches the end of a non-empty
+// input. When shuffle is set, every replayed pass is in a random order.
+func NewCyclicReader(reader Reader, shuffle bool) Reader {
+	return &CyclicReader{
+		reader:  reader,
+		shuffle: shuffle,
+	}
+}
+
+func (r *CyclicReader) Parse() (*model.QueryParams, error) {
+	if !r.replay {
+		params, err := r.reader.Parse()
+		if err == nil {
+			r.params = append(r.params, params)
+			return params, nil
+		}
+		if err != io.EOF || len(r.params) == 0 {
+			return nil, err
+		}
+		r.replay = true
+		r.next = len(r.params)
+	}
+
+	if r.next == len(r.params) {
+		r.next = 0
+		if r.shuffle {
+			rand.Shuffle(len(r.params), func(i, j int) { r.params[i], r.params[j] = r.params[j], r.params[i] })
+		}
+	}
+	params := r.params[r.next]
+	r.next++
+	return params, nil
+}
+
+func (r *CyclicReader) Close() error {
+	return r.reader.Close()
+}
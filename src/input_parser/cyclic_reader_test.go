@@ -0,0 +1,41 @@
+package inputparser
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCyclicReader_Parse(t *testing.T) {
+	input := `hostname,start_time,end_time
+host_000001,2017-01-01 08:59:22,2017-01-01 09:59:22
+host_000002,2017-01-01 08:59:22,2017-01-01 09:59:22
+host_000003,2017-01-01 08:59:22,2017-01-01 09:59:22`
+
+	for _, shuffle := range []bool{false, true} {
+		reader := NewCyclicReader(NewCSVStreamReader(strings.NewReader(input)), shuffle)
+
+		// Every pass reads each hostname exactly once.
+		for pass := 0; pass < 3; pass++ {
+			hostnames := make(map[string]int)
+			for i := 0; i < 3; i++ {
+				params, err := reader.Parse()
+				if !assert.NoError(t, err) {
+					return
+				}
+				hostnames[params.Hostname]++
+			}
+			assert.Equal(t, map[string]int{"host_000001": 1, "host_000002": 1, "host_000003": 1}, hostnames)
+		}
+		assert.NoError(t, reader.Close())
+	}
+}
+
+func TestCyclicReader_ParseEmpty(t *testing.T) {
+	reader := NewCyclicReader(NewCSVStreamReader(strings.NewReader("hostname,start_time,end_time\n")), false)
+
+	_, err := reader.Parse()
+	assert.Equal(t, io.EOF, err)
+}
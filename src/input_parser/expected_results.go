@@ -0,0 +1,76 @@
+package inputparser
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/molinama/timescale/src/model"
+)
+
+// expectedResultsHeader is the first record of an expected results CSV file.
+var expectedResultsHeader = []string{"hostname", "start_time", "end_time", "rows", "checksum"}
+
+// ReadExpectedResults reads an expected results CSV file, as written by
+// WriteExpectedResults.
+func ReadExpectedResults(path string) (*model.ExpectedResults, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = len(expectedResultsHeader)
+	expectedResults := model.NewExpectedResults()
+	for first := true; ; first = false {
+		data, err := reader.Read()
+		if err == io.EOF {
+			return expectedResults, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot read expected results: %w", err)
+		}
+		if first && data[0] == expectedResultsHeader[0] {
+			continue
+		}
+
+		params, err := model.NewQueryParams(data[:3])
+		if err != nil {
+			return nil, expectedResultsError(reader, err)
+		}
+		rows, err := strconv.Atoi(data[3])
+		if err != nil || rows < 0 {
+			return nil, expectedResultsError(reader, fmt.Errorf("invalid format: rows: %s is not a number of rows", data[3]))
+		}
+		expectedResults.Set(*params, model.ExpectedResult{Rows: rows, Checksum: data[4]})
+	}
+}
+
+// WriteExpectedResults writes the expected results to a CSV file.
+func WriteExpectedResults(path string, expectedResults *model.ExpectedResults) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	writer.Write(expectedResultsHeader)
+	for _, params := range expectedResults.Params() {
+		expected, _ := expectedResults.Get(params)
+		writer.Write([]string{params.Hostname, params.StartTime, params.EndTime, strconv.Itoa(expected.Rows), expected.Checksum})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func expectedResultsError(reader *csv.Reader, err error) error {
+	line, _ := reader.FieldPos(0)
+	return fmt.Errorf("cannot read expected results: %w", &RowError{Line: line, Err: err})
+}
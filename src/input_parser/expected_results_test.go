@@ -0,0 +1,60 @@
+package inputparser
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/molinama/timescale/src/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpectedResults_WriteRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "expected.csv")
+	expectedResults := model.NewExpectedResults()
+	expectedResults.Set(
+		model.QueryParams{Hostname: "host_000002", StartTime: "2017-01-01 08:59:22", EndTime: "2017-01-01 09:59:22"},
+		model.ExpectedResult{Rows: 60, Checksum: "0f1e"},
+	)
+	expectedResults.Set(
+		model.QueryParams{Hostname: "host_000001", StartTime: "2017-01-02 13:02:02", EndTime: "2017-01-02 14:02:02"},
+		model.ExpectedResult{Rows: 0, Checksum: "e3b0"},
+	)
+
+	if !assert.NoError(t, WriteExpectedResults(path, expectedResults)) {
+		return
+	}
+	data, err := os.ReadFile(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, `hostname,start_time,end_time,rows,checksum
+host_000001,2017-01-02 13:02:02,2017-01-02 14:02:02,0,e3b0
+host_000002,2017-01-01 08:59:22,2017-01-01 09:59:22,60,0f1e
+`, string(data))
+
+	got, err := ReadExpectedResults(path)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, expectedResults.Params(), got.Params())
+	for _, params := range got.Params() {
+		want, _ := expectedResults.Get(params)
+		result, _ := got.Get(params)
+		assert.Equal(t, want, result)
+	}
+}
+
+func TestReadExpectedResults_Invalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "expected.csv")
+	err := os.WriteFile(path, []byte("hostname,start_time,end_time,rows,checksum\nhost_000001,2017-01-02 13:02:02,2017-01-02 14:02:02,many,e3b0\n"), 0o644)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	_, err = ReadExpectedResults(path)
+	var rowErr *RowError
+	if assert.ErrorAs(t, err, &rowErr) {
+		assert.Equal(t, 2, rowErr.Line)
+	}
+}
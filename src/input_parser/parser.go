@@ -1,8 +1,27 @@
 package inputparser
 
-import "github.com/molinama/timescale/src/model"
+import (
+	"fmt"
+
+	"github.com/molinama/timescale/src/model"
+)
 
 type Reader interface {
 	Parse() (*model.QueryParams, error)
 	Close() error
 }
+
+// RowError reports an input row that could not be turned into query parameters.
+// Reading can continue with the next row after a RowError.
+type RowError struct {
+	Line int
+	Err  error
+}
+
+func (e *RowError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+func (e *RowError) Unwrap() error {
+	return e.Err
+}
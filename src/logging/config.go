@@ -2,6 +2,7 @@ package logging
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/molinama/timescale/src/utils"
 )
@@ -20,7 +21,7 @@ const (
 )
 
 func loadConfig() (*config, error) {
-	fmt.Println("Load Logging configurations")
+	fmt.Fprintln(os.Stderr, "Load Logging configurations")
 	return &config{
 		encoding: utils.GetStringEnv(logEncodingEnvVar, logEncodingDefault),
 		level:    utils.GetStringEnv(logLevelEnvVar, logLevelDefault),
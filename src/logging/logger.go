@@ -2,17 +2,20 @@ package logging
 
 import (
 	"fmt"
+	"os"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
 var Config *zap.Config
-var Log *zap.Logger
-var SugaredLog *zap.SugaredLogger
+
+// Log and SugaredLog discard everything until InitGlobalLogger is called.
+var Log = zap.NewNop()
+var SugaredLog = Log.Sugar()
 
 func InitGlobalLogger() error {
-	fmt.Println("Initialize global logger")
+	fmt.Fprintln(os.Stderr, "Initialize global logger")
 
 	cfg, cfgErr := loadConfig()
 	if cfgErr != nil {
@@ -27,7 +30,7 @@ func InitGlobalLogger() error {
 	Config = &zap.Config{
 		Encoding:         cfg.encoding,
 		Level:            zap.NewAtomicLevelAt(level),
-		OutputPaths:      []string{"stdout"},
+		OutputPaths:      []string{"stderr"},
 		ErrorOutputPaths: []string{"stderr"},
 		EncoderConfig:    buildEncoderConfig(level),
 	}
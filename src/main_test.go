@@ -1,11 +1,21 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
+	inputparser "github.com/molinama/timescale/src/input_parser"
+	"github.com/molinama/timescale/src/model"
+	"github.com/stretchr/testify/assert"
 )
 
 func Test_run(t *testing.T) {
@@ -52,3 +62,207 @@ host_000008,2017-01-02 18:50:28,2017-01-02 19:50:28`
 		t.Fatalf("Error running main function: %v", err)
 	}
 }
+
+func writeTempCSV(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "query_params.csv")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("Error writing temp CSV file: %v", err)
+	}
+	return path
+}
+
+func Test_validate(t *testing.T) {
+	path := writeTempCSV(t, `hostname,start_time,end_time
+host_000008,2017-01-01 08:59:22,2017-01-01 09:59:22
+host_000001,2017-01-02
+,2017-01-02 18:50:28,2017-01-02 19:50:28`)
+
+	var output strings.Builder
+	err := validate(Config{csvFilePath: path}, &output)
+	assert.EqualError(t, err, "2 invalid input rows")
+	assert.Equal(t, "Valid input rows: 1\n"+
+		"Rejected input rows: 2\n"+
+		"  line 3: invalid format: expected exactly 3 elements\n"+
+		"  line 4: invalid format: hostname cannot be empty\n", output.String())
+
+	output.Reset()
+	path = writeTempCSV(t, "host_000008,2017-01-01 08:59:22,2017-01-01 09:59:22\n")
+	assert.NoError(t, validate(Config{csvFilePath: path}, &output))
+	assert.Equal(t, "Valid input rows: 1\nRejected input rows: 0\n", output.String())
+
+	// A file that cannot be opened is reported, not only logged.
+	output.Reset()
+	err = validate(Config{csvFilePath: filepath.Join(t.TempDir(), "missing.csv")}, &output)
+	assert.ErrorContains(t, err, "cannot open CSV file")
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	assert.Empty(t, output.String())
+}
+
+func Test_runStrict(t *testing.T) {
+	path := writeTempCSV(t, `hostname,start_time,end_time
+host_000008,2017-01-01 08:59:22,2017-01-01 09:59:22
+host_000001,2017-01-02`)
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	err = run(Config{csvFilePath: path, numberWorkers: 2, strict: true, db: db})
+	var rowErr *inputparser.RowError
+	if assert.ErrorAs(t, err, &rowErr) {
+		assert.Equal(t, 3, rowErr.Line)
+	}
+}
+
+func Test_sweep(t *testing.T) {
+	path := writeTempCSV(t, `hostname,start_time,end_time
+host_000008,2017-01-01 08:59:22,2017-01-01 09:59:22
+host_000001,2017-01-02 13:02:02,2017-01-02 14:02:02`)
+	outputPath := filepath.Join(t.TempDir(), "sweep.json")
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	err = sweep(Config{csvFilePath: path, sweep: []int{1, 2, 4}, outputFormat: OUTPUT_JSON, outputFile: outputPath, db: db})
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	data, err := os.ReadFile(outputPath)
+	if err != nil {
+		t.Fatalf("Error reading sweep output: %v", err)
+	}
+	var output struct {
+		Steps []struct {
+			Workers int `json:"workers"`
+			Stats   struct {
+				TotalQueries int `json:"total_queries"`
+			} `json:"stats"`
+		} `json:"steps"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &output))
+	if assert.Len(t, output.Steps, 3) {
+		for i, workers := range []int{1, 2, 4} {
+			assert.Equal(t, workers, output.Steps[i].Workers)
+			assert.Equal(t, 2, output.Steps[i].Stats.TotalQueries)
+		}
+	}
+}
+
+func Test_sweepRejectsSingleRunFiles(t *testing.T) {
+	path := writeTempCSV(t, `hostname,start_time,end_time
+host_000008,2017-01-01 08:59:22,2017-01-01 09:59:22`)
+
+	err := sweep(Config{csvFilePath: path, sweep: []int{1, 2}, interval: time.Second, seriesFile: filepath.Join(t.TempDir(), "series.csv")})
+	assert.ErrorContains(t, err, "-interval-file")
+
+	err = sweep(Config{csvFilePath: path, sweep: []int{1, 2}, recordFile: filepath.Join(t.TempDir(), "expected.csv")})
+	assert.ErrorContains(t, err, "-record-expected")
+}
+
+func Test_runInterval(t *testing.T) {
+	path := writeTempCSV(t, `hostname,start_time,end_time
+host_000008,2017-01-01 08:59:22,2017-01-01 09:59:22
+host_000001,2017-01-02 13:02:02,2017-01-02 14:02:02`)
+	seriesPath := filepath.Join(t.TempDir(), "series.csv")
+
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	defer db.Close()
+
+	config := Config{
+		csvFilePath:   path,
+		numberWorkers: 2,
+		interval:      time.Hour,
+		seriesFile:    seriesPath,
+		seriesFormat:  OUTPUT_CSV,
+		percentiles:   []float64{99},
+		outputFile:    filepath.Join(t.TempDir(), "stats.txt"),
+		db:            db,
+	}
+	if !assert.NoError(t, run(config)) {
+		return
+	}
+
+	data, err := os.ReadFile(seriesPath)
+	if err != nil {
+		t.Fatalf("Error reading interval file: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(data)), "\n")
+	assert.Equal(t, "start,count,errors,throughput,min_ns,median_ns,avg_ns,max_ns,p99_ns", lines[0])
+	// The intervals start with the run, so both queries complete within the first
+	// hour. They fail as the test database has no table.
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, []string{"0", "2"}, strings.Split(lines[1], ",")[1:3])
+	}
+}
+
+// hangingRepository runs queries until they are canceled, as queries stuck in
+// the database.
+type hangingRepository struct {
+	started chan struct{}
+	done    sync.WaitGroup
+}
+
+func (r *hangingRepository) RawQuery(ctx context.Context, params *model.QueryParams) (model.QueryResponse, error) {
+	defer r.done.Done()
+	r.started <- struct{}{}
+	<-ctx.Done()
+	return model.QueryResponse{}, ctx.Err()
+}
+
+func Test_benchmarkGracePeriodExpired(t *testing.T) {
+	path := writeTempCSV(t, `hostname,start_time,end_time
+host_000008,2017-01-01 08:59:22,2017-01-01 09:59:22`)
+	reader, err := inputparser.NewCSVReader(path)
+	if err != nil {
+		t.Fatalf("Error opening CSV file: %v", err)
+	}
+	defer reader.Close()
+
+	repository := &hangingRepository{started: make(chan struct{}, 1)}
+	repository.done.Add(1)
+	go func() {
+		<-repository.started
+		syscall.Kill(os.Getpid(), syscall.SIGINT)
+	}()
+
+	stats, err := benchmark(Config{numberWorkers: 1, gracePeriod: 10 * time.Millisecond}, repository, reader)
+	if !assert.NoError(t, err) {
+		return
+	}
+	// The canceled query is not counted, whether it completes before or after
+	// the stats are calculated.
+	repository.done.Wait()
+	assert.True(t, stats.Partial)
+	assert.Equal(t, 0, stats.TotalSuccess)
+	assert.Equal(t, 0, stats.TotalErrs)
+}
+
+func Test_reportProgress(t *testing.T) {
+	progress := model.NewProgress(2, 0)
+	progress.Record(model.QueryTaskResult{Duration: time.Millisecond})
+
+	var output strings.Builder
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reportProgress(ctx, progress, 5*time.Millisecond, &output)
+	}()
+	time.Sleep(30 * time.Millisecond)
+	cancel()
+	<-done
+
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.GreaterOrEqual(t, len(lines), 2)
+	assert.Contains(t, lines[0], "1/2 queries (50.0%)")
+	assert.Contains(t, lines[0], "p50 1ms")
+}
@@ -0,0 +1,93 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+// Dimensions of the stats breakdown.
+const (
+	BreakdownWorker   = "worker"
+	BreakdownHostname = "host"
+)
+
+// ParseBreakdown parses a comma separated list of breakdown dimensions such as "worker,host".
+func ParseBreakdown(value string) ([]string, error) {
+	var dimensions []string
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		switch field {
+		case "":
+			continue
+		case BreakdownWorker, BreakdownHostname:
+			dimensions = append(dimensions, field)
+		default:
+			return nil, fmt.Errorf("invalid breakdown: %s (expected %s or %s)", field, BreakdownWorker, BreakdownHostname)
+		}
+	}
+	return dimensions, nil
+}
+
+// Breakdown renders a table with the stats of every worker or hostname, sorted
+// by worker or hostname.
+func (qs Stats) Breakdown(dimension string) string {
+	var header string
+	var rows []string
+	statsByRow := make(map[string]*queryStats)
+
+	switch dimension {
+	case BreakdownWorker:
+		header = "Worker"
+		workers := make([]Worker, 0, len(qs.QueryWorkerStats))
+		for worker := range qs.QueryWorkerStats {
+			workers = append(workers, worker)
+		}
+		sort.Slice(workers, func(i, j int) bool { return workers[i] < workers[j] })
+		for _, worker := range workers {
+			row := fmt.Sprint(worker)
+			rows = append(rows, row)
+			statsByRow[row] = &qs.QueryWorkerStats[worker].queryStats
+		}
+	case BreakdownHostname:
+		header = "Hostname"
+		for hostname, hostnameStats := range qs.QueryHostnameStats {
+			rows = append(rows, hostname)
+			statsByRow[hostname] = hostnameStats
+		}
+		sort.Strings(rows)
+	}
+
+	var table strings.Builder
+	fmt.Fprintf(&table, "\n%s STATS\n\n", strings.ToUpper(header))
+
+	writer := tabwriter.NewWriter(&table, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintf(writer, "%s\tCount\tQueries/s\tRows\tEmpty\tMin\tMedian\tAvg\tMax\t", header)
+	for _, percentile := range qs.Percentiles {
+		fmt.Fprintf(writer, "%s\t", percentile.Label())
+	}
+	fmt.Fprintln(writer)
+
+	for _, row := range rows {
+		rowStats := statsByRow[row]
+		fmt.Fprintf(writer, "%s\t%d\t%.1f\t%d\t%d\t%v\t%v\t%v\t%v\t",
+			row,
+			rowStats.TotalSuccess,
+			rowStats.Throughput,
+			rowStats.TotalRows,
+			rowStats.EmptyResults,
+			rowStats.MinQueryTime,
+			rowStats.MedianQueryTime,
+			rowStats.AvgQueryTime,
+			rowStats.MaxQueryTime,
+		)
+		for _, percentile := range rowStats.Percentiles {
+			fmt.Fprintf(writer, "%v\t", percentile.QueryTime)
+		}
+		fmt.Fprintln(writer)
+	}
+	writer.Flush()
+
+	return table.String()
+}
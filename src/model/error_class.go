@@ -0,0 +1,192 @@
+package model
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Classes of the query errors that have no SQLSTATE code.
+const (
+	ErrorClassConnection   = "connection"
+	ErrorClassTimeout      = "timeout"
+	ErrorClassVerification = "verification"
+	ErrorClassParse        = "parse"
+	ErrorClassCanceled     = "canceled"
+	ErrorClassOther        = "other"
+)
+
+// maxHostnames is the number of hostnames listed for an error class in the text output.
+const maxHostnames = 5
+
+// ErrorClassStats aggregates the errors of one class. The first occurrence and
+// its error message are kept along with an example query, which is empty for
+// parse errors.
+type ErrorClassStats struct {
+	Class        string
+	Count        int
+	FirstTime    time.Time
+	FirstErr     string
+	ExampleQuery string
+	Workers      map[Worker]int
+	Hostnames    map[string]int
+}
+
+// ClassifyError returns the class of a query error: the SQLSTATE code reported
+// by the database, such as "SQLSTATE 53300", or one of the ErrorClass constants.
+func ClassifyError(err error) string {
+	var timeoutErr *TimeoutError
+	var verificationErr *VerificationError
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.As(err, &timeoutErr):
+		return ErrorClassTimeout
+	case errors.As(err, &verificationErr):
+		return ErrorClassVerification
+	case errors.As(err, &pgErr):
+		return "SQLSTATE " + pgErr.Code
+	case errors.Is(err, context.Canceled):
+		return ErrorClassCanceled
+	case IsConnectionError(err):
+		return ErrorClassConnection
+	default:
+		return ErrorClassOther
+	}
+}
+
+// IsConnectionError reports whether the error comes from a broken or refused
+// connection rather than from the query.
+func IsConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.As(err, &netErr) ||
+		pgconn.SafeToRetry(err)
+}
+
+// errorClassRecord groups the query and parse errors by class as they are
+// recorded, so that only one ErrorClassStats is kept per class.
+type errorClassRecord map[string]*ErrorClassStats
+
+func (r errorClassRecord) class(class string) *ErrorClassStats {
+	stats, exists := r[class]
+	if !exists {
+		stats = &ErrorClassStats{
+			Class:     class,
+			Workers:   make(map[Worker]int),
+			Hostnames: make(map[string]int),
+		}
+		r[class] = stats
+	}
+	stats.Count++
+	return stats
+}
+
+func (r errorClassRecord) recordQueryTaskErr(queryTaskErr QueryTaskErr) {
+	stats := r.class(ClassifyError(queryTaskErr.Err))
+	if stats.Count == 1 || queryTaskErr.Time.Before(stats.FirstTime) {
+		stats.FirstTime = queryTaskErr.Time
+		stats.FirstErr = errorMessage(queryTaskErr.Err)
+		stats.ExampleQuery = queryTaskErr.RawQuery
+	}
+	stats.Workers[queryTaskErr.Worker]++
+	stats.Hostnames[queryTaskErr.Hostname]++
+}
+
+func (r errorClassRecord) recordParseErr(parseErr ParseErr) {
+	stats := r.class(ErrorClassParse)
+	if stats.Count == 1 || parseErr.Time.Before(stats.FirstTime) {
+		stats.FirstTime = parseErr.Time
+		stats.FirstErr = parseErr.Error()
+	}
+}
+
+// calculate returns the error classes sorted by descending count.
+func (r errorClassRecord) calculate() []ErrorClassStats {
+	errorClasses := make([]ErrorClassStats, 0, len(r))
+	for _, stats := range r {
+		errorClass := *stats
+		errorClass.Workers = make(map[Worker]int, len(stats.Workers))
+		for worker, count := range stats.Workers {
+			errorClass.Workers[worker] = count
+		}
+		errorClass.Hostnames = make(map[string]int, len(stats.Hostnames))
+		for hostname, count := range stats.Hostnames {
+			errorClass.Hostnames[hostname] = count
+		}
+		errorClasses = append(errorClasses, errorClass)
+	}
+	sort.Slice(errorClasses, func(i, j int) bool {
+		if errorClasses[i].Count != errorClasses[j].Count {
+			return errorClasses[i].Count > errorClasses[j].Count
+		}
+		return errorClasses[i].Class < errorClasses[j].Class
+	})
+	return errorClasses
+}
+
+// errorClassesSummary renders every error class with its first occurrence and
+// the distribution of its errors across workers and hostnames.
+func errorClassesSummary(errorClasses []ErrorClassStats) string {
+	var summary strings.Builder
+	summary.WriteString("\nERRORS BY CLASS\n")
+	for _, stats := range errorClasses {
+		fmt.Fprintf(&summary, "\n%s: %d\n", stats.Class, stats.Count)
+		fmt.Fprintf(&summary, "  First occurrence: %s: %s\n", stats.FirstTime.Format(time.RFC3339Nano), stats.FirstErr)
+		if stats.ExampleQuery != "" {
+			fmt.Fprintf(&summary, "  Example query: %s\n", stats.ExampleQuery)
+		}
+		if len(stats.Workers) > 0 {
+			workers := make([]Worker, 0, len(stats.Workers))
+			for worker := range stats.Workers {
+				workers = append(workers, worker)
+			}
+			sort.Slice(workers, func(i, j int) bool { return workers[i] < workers[j] })
+			counts := make([]string, 0, len(workers))
+			for _, worker := range workers {
+				counts = append(counts, fmt.Sprintf("%d=%d", worker, stats.Workers[worker]))
+			}
+			fmt.Fprintf(&summary, "  Workers: %s\n", strings.Join(counts, ", "))
+		}
+		if len(stats.Hostnames) > 0 {
+			hostnames := make([]string, 0, len(stats.Hostnames))
+			for hostname := range stats.Hostnames {
+				hostnames = append(hostnames, hostname)
+			}
+			sort.Slice(hostnames, func(i, j int) bool {
+				if stats.Hostnames[hostnames[i]] != stats.Hostnames[hostnames[j]] {
+					return stats.Hostnames[hostnames[i]] > stats.Hostnames[hostnames[j]]
+				}
+				return hostnames[i] < hostnames[j]
+			})
+			counts := make([]string, 0, maxHostnames)
+			for _, hostname := range hostnames[:min(len(hostnames), maxHostnames)] {
+				counts = append(counts, fmt.Sprintf("%s=%d", hostname, stats.Hostnames[hostname]))
+			}
+			if len(hostnames) > maxHostnames {
+				counts = append(counts, fmt.Sprintf("... (%d more)", len(hostnames)-maxHostnames))
+			}
+			fmt.Fprintf(&summary, "  Hostnames: %s\n", strings.Join(counts, ", "))
+		}
+	}
+	return summary.String()
+}
+
+func errorMessage(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
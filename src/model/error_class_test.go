@@ -0,0 +1,81 @@
+package model
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "SQLSTATE", err: fmt.Errorf("query: %w", &pgconn.PgError{Code: "42883"}), want: "SQLSTATE 42883"},
+		{name: "Timeout", err: &TimeoutError{Timeout: time.Second, Err: context.DeadlineExceeded}, want: ErrorClassTimeout},
+		{name: "Verification", err: &VerificationError{}, want: ErrorClassVerification},
+		{name: "Connection", err: driver.ErrBadConn, want: ErrorClassConnection},
+		{name: "Canceled", err: context.Canceled, want: ErrorClassCanceled},
+		{name: "Other", err: errors.New("no such function: time_bucket"), want: ErrorClassOther},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ClassifyError(tt.err))
+		})
+	}
+}
+
+func TestCalculateRecordedStatsErrorClasses(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tooManyConnections := &pgconn.PgError{Code: "53300", Message: "too many connections"}
+	recorder := NewRecorder()
+	recorder.RecordErr(QueryTaskErr{QueryTaskResult: QueryTaskResult{Worker: 2, Hostname: "host2"}, RawQuery: "SELECT 2", Time: start.Add(time.Second), Err: tooManyConnections})
+	recorder.RecordErr(QueryTaskErr{QueryTaskResult: QueryTaskResult{Worker: 1, Hostname: "host1"}, RawQuery: "SELECT 1", Time: start, Err: tooManyConnections})
+	recorder.RecordErr(QueryTaskErr{QueryTaskResult: QueryTaskResult{Worker: 1, Hostname: "host1"}, RawQuery: "SELECT 1", Time: start, Err: &TimeoutError{Timeout: time.Second, Err: context.DeadlineExceeded}})
+	recorder.RecordParseErr(3, errors.New("invalid format"))
+
+	qs := Stats{}
+	qs.CalculateRecordedStats(recorder)
+	assert.Equal(t, 3, qs.TotalErrs)
+	if !assert.Len(t, qs.ErrorClasses, 3) {
+		return
+	}
+	assert.Equal(t, ErrorClassStats{
+		Class:        "SQLSTATE 53300",
+		Count:        2,
+		FirstTime:    start,
+		FirstErr:     tooManyConnections.Error(),
+		ExampleQuery: "SELECT 1",
+		Workers:      map[Worker]int{1: 1, 2: 1},
+		Hostnames:    map[string]int{"host1": 1, "host2": 1},
+	}, qs.ErrorClasses[0])
+	assert.Equal(t, ErrorClassParse, qs.ErrorClasses[1].Class)
+	assert.Equal(t, "line 3: invalid format", qs.ErrorClasses[1].FirstErr)
+	assert.Equal(t, ErrorClassTimeout, qs.ErrorClasses[2].Class)
+
+	summary := qs.String()
+	assert.Contains(t, summary, "ERRORS BY CLASS")
+	assert.Regexp(t, `(?s)SQLSTATE 53300: 2\n.*Example query: SELECT 1\n\s+Workers: 1=1, 2=1\n\s+Hostnames: host1=1, host2=1\n`, summary)
+
+	data, err := json.Marshal(qs)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var report struct {
+		ErrorClasses []map[string]any `json:"error_classes"`
+		Errors       []map[string]any `json:"errors"`
+	}
+	if assert.NoError(t, json.Unmarshal(data, &report)) && assert.Len(t, report.ErrorClasses, 3) {
+		assert.Equal(t, "SQLSTATE 53300", report.ErrorClasses[0]["class"])
+		assert.Equal(t, map[string]any{"1": float64(1), "2": float64(1)}, report.ErrorClasses[0]["workers"])
+		assert.Equal(t, ErrorClassTimeout, report.Errors[2]["class"])
+	}
+}
@@ -0,0 +1,99 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ExpectedResult is the expected result set of a query, as its number of rows
+// and the checksum of its ordered rows.
+type ExpectedResult struct {
+	Rows     int
+	Checksum string
+}
+
+// ExpectedResults holds the expected result of the queries, keyed by their
+// params. ExpectedResults is safe for concurrent use.
+type ExpectedResults struct {
+	mu      sync.RWMutex
+	results map[QueryParams]ExpectedResult
+}
+
+// VerificationError reports a query that returned a different result set than
+// the expected one.
+type VerificationError struct {
+	Expected ExpectedResult
+	Actual   ExpectedResult
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("result mismatch: expected %d rows with checksum %s, got %d rows with checksum %s",
+		e.Expected.Rows, e.Expected.Checksum, e.Actual.Rows, e.Actual.Checksum)
+}
+
+func NewExpectedResults() *ExpectedResults {
+	return &ExpectedResults{
+		results: make(map[QueryParams]ExpectedResult),
+	}
+}
+
+func (e *ExpectedResults) Set(params QueryParams, result ExpectedResult) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.results[params] = result
+}
+
+func (e *ExpectedResults) Get(params QueryParams) (ExpectedResult, bool) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	result, exists := e.results[params]
+	return result, exists
+}
+
+func (e *ExpectedResults) Len() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return len(e.results)
+}
+
+// Params returns the params of all the expected results, sorted by hostname,
+// start time and end time.
+func (e *ExpectedResults) Params() []QueryParams {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	params := make([]QueryParams, 0, len(e.results))
+	for p := range e.results {
+		params = append(params, p)
+	}
+	sort.Slice(params, func(i, j int) bool {
+		if params[i].Hostname != params[j].Hostname {
+			return params[i].Hostname < params[j].Hostname
+		}
+		if params[i].StartTime != params[j].StartTime {
+			return params[i].StartTime < params[j].StartTime
+		}
+		return params[i].EndTime < params[j].EndTime
+	})
+	return params
+}
+
+// Verify checks the response against the expected result of the params. It
+// reports whether there was an expected result to check, and a
+// *VerificationError when the response does not match it.
+func (e *ExpectedResults) Verify(params *QueryParams, response QueryResponse) (bool, error) {
+	expected, exists := e.Get(*params)
+	if !exists {
+		return false, nil
+	}
+
+	actual := ExpectedResult{Rows: response.Rows, Checksum: response.Checksum}
+	if actual != expected {
+		return true, &VerificationError{Expected: expected, Actual: actual}
+	}
+	return true, nil
+}
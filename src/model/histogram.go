@@ -0,0 +1,176 @@
+package model
+
+import (
+	"math"
+	"math/bits"
+	"sort"
+	"time"
+)
+
+// The histogram keeps subBucketCount linear sub-buckets per power of two, so a
+// recorded query time is reported with a relative error below 1/subBucketHalfCount (~0.1%).
+const (
+	subBucketBits      = 11
+	subBucketCount     = 1 << subBucketBits
+	subBucketHalfCount = subBucketCount / 2
+)
+
+// Histogram is an HDR-style log-linear histogram of query times. Only the
+// buckets that were hit are stored, so its size does not grow with the number
+// of recorded values. Histogram is not safe for concurrent use.
+type Histogram struct {
+	counts map[int]int
+	total  int
+	sum    time.Duration
+	min    time.Duration
+	max    time.Duration
+}
+
+func NewHistogram() *Histogram {
+	return &Histogram{
+		counts: make(map[int]int),
+	}
+}
+
+func (h *Histogram) Record(queryTime time.Duration) {
+	if queryTime < 0 {
+		queryTime = 0
+	}
+	if h.total == 0 || queryTime < h.min {
+		h.min = queryTime
+	}
+	if queryTime > h.max {
+		h.max = queryTime
+	}
+	h.counts[bucketIndex(int64(queryTime))]++
+	h.total++
+	h.sum += queryTime
+}
+
+// Merge adds all the values recorded in other to h.
+func (h *Histogram) Merge(other *Histogram) {
+	if other.total == 0 {
+		return
+	}
+	if h.total == 0 || other.min < h.min {
+		h.min = other.min
+	}
+	if other.max > h.max {
+		h.max = other.max
+	}
+	for index, count := range other.counts {
+		h.counts[index] += count
+	}
+	h.total += other.total
+	h.sum += other.sum
+}
+
+func (h *Histogram) Count() int {
+	return h.total
+}
+
+func (h *Histogram) Sum() time.Duration {
+	return h.sum
+}
+
+func (h *Histogram) Min() time.Duration {
+	return h.min
+}
+
+func (h *Histogram) Max() time.Duration {
+	return h.max
+}
+
+func (h *Histogram) Mean() time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+	return h.sum / time.Duration(h.total)
+}
+
+// ValueAtPercentile returns the nearest-rank percentile of the recorded values.
+func (h *Histogram) ValueAtPercentile(percentile float64) time.Duration {
+	return h.valuesAtPercentiles([]float64{percentile})[0]
+}
+
+// Median returns the middle query time, the mean of the two middle ones when
+// the count is even.
+func (h *Histogram) Median() time.Duration {
+	if h.total%2 == 1 {
+		return h.valuesAtRanks([]int{h.total/2 + 1})[0]
+	}
+	values := h.valuesAtRanks([]int{h.total / 2, h.total/2 + 1})
+	return (values[0] + values[1]) / 2
+}
+
+func (h *Histogram) valuesAtPercentiles(percentiles []float64) []time.Duration {
+	ranks := make([]int, len(percentiles))
+	for i, percentile := range percentiles {
+		// The epsilon keeps float rounding (e.g. 99.9% of 1000) from moving up a rank.
+		ranks[i] = int(math.Ceil(percentile*float64(h.total)/100 - 1e-9))
+	}
+	return h.valuesAtRanks(ranks)
+}
+
+// valuesAtRanks returns the query times at the 1-based ranks, in the order of
+// the ranks.
+func (h *Histogram) valuesAtRanks(ranks []int) []time.Duration {
+	values := make([]time.Duration, len(ranks))
+	if h.total == 0 {
+		return values
+	}
+
+	indexes := make([]int, 0, len(h.counts))
+	for index := range h.counts {
+		indexes = append(indexes, index)
+	}
+	sort.Ints(indexes)
+
+	for i, rank := range ranks {
+		if rank < 1 {
+			rank = 1
+		}
+
+		seen := 0
+		for _, index := range indexes {
+			seen += h.counts[index]
+			if seen >= rank {
+				values[i] = h.clamp(bucketHighestValue(index))
+				break
+			}
+		}
+	}
+	return values
+}
+
+func (h *Histogram) clamp(value int64) time.Duration {
+	if time.Duration(value) < h.min {
+		return h.min
+	}
+	if time.Duration(value) > h.max {
+		return h.max
+	}
+	return time.Duration(value)
+}
+
+func bucketIndex(value int64) int {
+	if value < subBucketCount {
+		return int(value)
+	}
+	shift := bits.Len64(uint64(value)) - subBucketBits
+	subBucket := int(value >> shift)
+	return subBucketCount + (shift-1)*subBucketHalfCount + (subBucket - subBucketHalfCount)
+}
+
+func bucketHighestValue(index int) int64 {
+	if index < subBucketCount {
+		return int64(index)
+	}
+	shift := (index-subBucketCount)/subBucketHalfCount + 1
+	subBucket := uint64((index-subBucketCount)%subBucketHalfCount + subBucketHalfCount)
+	highest := (subBucket+1)<<shift - 1
+	if highest > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return int64(highest)
+}
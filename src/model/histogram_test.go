@@ -0,0 +1,80 @@
+package model
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogram(t *testing.T) {
+	histogram := NewHistogram()
+	for i := 1; i <= 100; i++ {
+		histogram.Record(time.Duration(i) * time.Millisecond)
+	}
+
+	assert.Equal(t, 100, histogram.Count())
+	assert.Equal(t, 5050*time.Millisecond, histogram.Sum())
+	assert.Equal(t, 1*time.Millisecond, histogram.Min())
+	assert.Equal(t, 100*time.Millisecond, histogram.Max())
+	assert.Equal(t, 50500*time.Microsecond, histogram.Mean())
+	assertQueryTime(t, 50*time.Millisecond, histogram.ValueAtPercentile(50))
+	assertQueryTime(t, 99*time.Millisecond, histogram.ValueAtPercentile(99))
+	assert.Equal(t, 100*time.Millisecond, histogram.ValueAtPercentile(100))
+}
+
+func TestHistogram_Median(t *testing.T) {
+	histogram := NewHistogram()
+	assert.Equal(t, time.Duration(0), histogram.Median())
+
+	for _, queryTime := range []time.Duration{4 * time.Second, 2 * time.Second, 3 * time.Second} {
+		histogram.Record(queryTime)
+	}
+	assertQueryTime(t, 3*time.Second, histogram.Median())
+
+	histogram.Record(5 * time.Second)
+	assertQueryTime(t, (3*time.Second+4*time.Second)/2, histogram.Median())
+}
+
+func TestHistogram_Merge(t *testing.T) {
+	odd := NewHistogram()
+	even := NewHistogram()
+	all := NewHistogram()
+	for i := 1; i <= 1000; i++ {
+		queryTime := time.Duration(i) * time.Microsecond
+		if i%2 == 0 {
+			even.Record(queryTime)
+		} else {
+			odd.Record(queryTime)
+		}
+		all.Record(queryTime)
+	}
+
+	merged := NewHistogram()
+	merged.Merge(odd)
+	merged.Merge(even)
+	merged.Merge(NewHistogram())
+
+	assert.Equal(t, all, merged)
+}
+
+func TestHistogram_Empty(t *testing.T) {
+	histogram := NewHistogram()
+
+	assert.Equal(t, 0, histogram.Count())
+	assert.Equal(t, time.Duration(0), histogram.Mean())
+	assert.Equal(t, time.Duration(0), histogram.ValueAtPercentile(99))
+}
+
+func TestBucketIndex(t *testing.T) {
+	values := []int64{0, 1, subBucketCount - 1, subBucketCount, 12345, int64(time.Second), int64(time.Hour), math.MaxInt64}
+	for _, value := range values {
+		index := bucketIndex(value)
+		highest := bucketHighestValue(index)
+
+		assert.GreaterOrEqual(t, highest, value)
+		assert.Equal(t, index, bucketIndex(highest))
+		assert.LessOrEqual(t, float64(highest-value), float64(value)/subBucketHalfCount)
+	}
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultPercentiles are the query time percentiles reported when none are configured.
+var DefaultPercentiles = []float64{50, 90, 95, 99, 99.9}
+
+type Percentile struct {
+	Percentile float64
+	QueryTime  time.Duration
+}
+
+func (p Percentile) Label() string {
+	return "p" + strconv.FormatFloat(p.Percentile, 'f', -1, 64)
+}
+
+// ParsePercentiles parses a comma separated list of percentiles such as "50,90,99,99.9".
+// The result is sorted and without duplicates.
+func ParsePercentiles(value string) ([]float64, error) {
+	var percentiles []float64
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		percentile, err := strconv.ParseFloat(field, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid percentile: %s is not a number", field)
+		}
+		if math.IsNaN(percentile) || math.IsInf(percentile, 0) || percentile <= 0 || percentile > 100 {
+			return nil, fmt.Errorf("invalid percentile: %s is not in the range (0, 100]", field)
+		}
+		percentiles = append(percentiles, percentile)
+	}
+
+	sort.Float64s(percentiles)
+	unique := percentiles[:0]
+	for i, percentile := range percentiles {
+		if i == 0 || percentile != percentiles[i-1] {
+			unique = append(unique, percentile)
+		}
+	}
+	return unique, nil
+}
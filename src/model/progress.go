@@ -0,0 +1,95 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Progress counts the completed queries of a running benchmark and keeps the
+// query times since the last report, so that the current throughput and query
+// times can be reported while the run goes on. Progress is safe for concurrent use.
+type Progress struct {
+	mu          sync.Mutex
+	start       time.Time
+	total       int
+	duration    time.Duration
+	completed   int
+	errs        int
+	window      *Histogram
+	windowStart time.Time
+}
+
+// NewProgress returns a Progress of a run of total queries, or of the given
+// duration. Both are zero when unknown.
+func NewProgress(total int, duration time.Duration) *Progress {
+	now := time.Now()
+	return &Progress{
+		start:       now,
+		total:       total,
+		duration:    duration,
+		window:      NewHistogram(),
+		windowStart: now,
+	}
+}
+
+// SetTotal sets the total number of queries of the run once it is known.
+func (p *Progress) SetTotal(total int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.total = total
+}
+
+func (p *Progress) Record(result QueryTaskResult) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.completed++
+	p.window.Record(result.Duration)
+}
+
+func (p *Progress) RecordErr() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.completed++
+	p.errs++
+}
+
+// Report returns a line with the queries completed so far, and the throughput
+// and the query times since the previous report. It then starts a new window.
+func (p *Progress) Report(now time.Time) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var line strings.Builder
+	elapsed := now.Sub(p.start).Round(time.Second)
+	if p.duration > 0 {
+		fmt.Fprintf(&line, "[%v/%v] ", elapsed, p.duration)
+	} else {
+		fmt.Fprintf(&line, "[%v] ", elapsed)
+	}
+	if p.total > 0 {
+		fmt.Fprintf(&line, "%d/%d queries (%.1f%%)", p.completed, p.total, float64(p.completed)*100/float64(p.total))
+	} else {
+		fmt.Fprintf(&line, "%d queries", p.completed)
+	}
+
+	var throughput float64
+	if window := now.Sub(p.windowStart); window > 0 {
+		throughput = float64(p.window.Count()) / window.Seconds()
+	}
+	values := p.window.valuesAtPercentiles([]float64{50, 99})
+	fmt.Fprintf(&line, ", %.1f queries/s, p50 %v, p99 %v, %d errors",
+		throughput,
+		values[0].Round(time.Microsecond),
+		values[1].Round(time.Microsecond),
+		p.errs,
+	)
+
+	p.window = NewHistogram()
+	p.windowStart = now
+	return line.String()
+}
@@ -0,0 +1,36 @@
+package model
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgress_Report(t *testing.T) {
+	progress := NewProgress(10, 0)
+	start := progress.start
+	progress.Record(QueryTaskResult{Duration: 2 * time.Millisecond})
+	progress.Record(QueryTaskResult{Duration: 4 * time.Millisecond})
+	progress.RecordErr()
+
+	assert.Equal(t, "[2s] 3/10 queries (30.0%), 1.0 queries/s, p50 2.001ms, p99 4ms, 1 errors", progress.Report(start.Add(2*time.Second)))
+	// The throughput and the query times only cover the queries since the previous report.
+	progress.Record(QueryTaskResult{Duration: 8 * time.Millisecond})
+	assert.Equal(t, "[3s] 4/10 queries (40.0%), 1.0 queries/s, p50 8ms, p99 8ms, 1 errors", progress.Report(start.Add(3*time.Second)))
+	assert.Equal(t, "[4s] 4/10 queries (40.0%), 0.0 queries/s, p50 0s, p99 0s, 1 errors", progress.Report(start.Add(4*time.Second)))
+
+	progress = NewProgress(0, time.Minute)
+	progress.Record(QueryTaskResult{Duration: time.Millisecond})
+	assert.Equal(t, "[10s/1m0s] 1 queries, 0.1 queries/s, p50 1ms, p99 1ms, 0 errors", progress.Report(progress.start.Add(10*time.Second)))
+}
+
+func TestProgress_SetTotal(t *testing.T) {
+	progress := NewProgress(0, 0)
+	progress.Record(QueryTaskResult{Duration: time.Millisecond})
+	assert.Contains(t, progress.Report(progress.start.Add(time.Second)), "] 1 queries,")
+
+	// The total is shown once it is known.
+	progress.SetTotal(4)
+	assert.Contains(t, progress.Report(progress.start.Add(2*time.Second)), "] 1/4 queries (25.0%),")
+}
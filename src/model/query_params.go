@@ -53,8 +53,9 @@ func validate(data []string) error {
 	return nil
 }
 
-func (params *QueryParams) RawQuery() string {
-	return fmt.Sprintf(`
+// queryTemplate is the benchmarked query. Its $1, $2 and $3 placeholders are bound
+// to the hostname, the start time and the end time.
+const queryTemplate = `
 	SELECT 
 		time_bucket('1 minute', ts) AS minute, 
 		MAX(usage) AS max_cpu_usage, 
@@ -62,12 +63,33 @@ func (params *QueryParams) RawQuery() string {
 	FROM 
 		cpu_usage 
 	WHERE 
-		host = '%s' AND 
-		ts >= '%s' AND 
-		ts <= '%s' 
+		host = $1 AND 
+		ts >= $2 AND 
+		ts <= $3 
 	GROUP BY 
 		minute 
 	ORDER BY 
 		minute;
-	`, params.Hostname, params.StartTime, params.EndTime)
+	`
+
+func (params *QueryParams) QueryTemplate() string {
+	return queryTemplate
+}
+
+// Args returns the arguments bound to the query template placeholders.
+func (params *QueryParams) Args() []any {
+	return []any{params.Hostname, params.StartTime, params.EndTime}
+}
+
+// RawQuery renders the query with its arguments quoted as SQL literals. It is meant
+// for diagnostics only, queries are executed with QueryTemplate and Args.
+func (params *QueryParams) RawQuery() string {
+	rawQuery := queryTemplate
+	args := params.Args()
+	// Replace from the last placeholder so $1 does not match the start of $10.
+	for i := len(args) - 1; i >= 0; i-- {
+		literal := "'" + strings.ReplaceAll(fmt.Sprint(args[i]), "'", "''") + "'"
+		rawQuery = strings.ReplaceAll(rawQuery, fmt.Sprintf("$%d", i+1), literal)
+	}
+	return rawQuery
 }
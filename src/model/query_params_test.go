@@ -81,7 +81,7 @@ func TestNewQueryParams(t *testing.T) {
 			args: args{
 				data: []string{"host_000008", "2017-01-01", "2017-01-01 09:59:22"},
 			},
-			wantErr: errors.New("invalid format: startTime is not in the correct format (expected 2006-01-02 15:04:05)"),
+			wantErr: errors.New("invalid format: startTime: 2017-01-01 is not in the correct format (expected 2006-01-02 15:04:05)"),
 		},
 	}
 	for _, tt := range tests {
@@ -107,7 +107,7 @@ func TestQueryParams_RawQuery(t *testing.T) {
 	tests := []struct {
 		name string
 		args args
-		want string
+		want []string
 	}{
 		{
 			name: "Valid QueryParams",
@@ -118,27 +118,45 @@ func TestQueryParams_RawQuery(t *testing.T) {
 					EndTime:   "2017-01-01 09:59:22",
 				},
 			},
-			want: `
-		SELECT
-			time_bucket('1 minute', ts) AS minute,
-			MAX(usage) AS max_cpu_usage,
-			MIN(usage) AS min_cpu_usage
-		FROM
-			metrics
-		WHERE
-			host = 'host_000008' AND
-			ts >= '2017-01-01 08:59:22' AND
-			ts <= '2017-01-01 09:59:22'
-		GROUP BY
-			minute
-		ORDER BY
-			minute;
-		`,
+			want: []string{
+				"FROM \n\t\tcpu_usage",
+				"host = 'host_000008' AND",
+				"ts >= '2017-01-01 08:59:22' AND",
+				"ts <= '2017-01-01 09:59:22'",
+			},
+		},
+		{
+			name: "Hostname With Quote",
+			args: args{
+				params: QueryParams{
+					Hostname:  "host' OR '1'='1",
+					StartTime: "2017-01-01 08:59:22",
+					EndTime:   "2017-01-01 09:59:22",
+				},
+			},
+			want: []string{
+				"host = 'host'' OR ''1''=''1' AND",
+			},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			assert.Equal(t, tt.want, tt.args.params.RawQuery())
+			rawQuery := tt.args.params.RawQuery()
+			for _, want := range tt.want {
+				assert.Contains(t, rawQuery, want)
+			}
 		})
 	}
 }
+
+func TestQueryParams_QueryTemplate(t *testing.T) {
+	params := QueryParams{
+		Hostname:  "host' OR '1'='1",
+		StartTime: "2017-01-01 08:59:22",
+		EndTime:   "2017-01-01 09:59:22",
+	}
+
+	assert.Contains(t, params.QueryTemplate(), "host = $1 AND")
+	assert.NotContains(t, params.QueryTemplate(), params.Hostname)
+	assert.Equal(t, []any{params.Hostname, params.StartTime, params.EndTime}, params.Args())
+}
@@ -0,0 +1,18 @@
+package model
+
+import "time"
+
+// QueryResponse is what the repository measured while running a query and
+// reading all of its rows.
+type QueryResponse struct {
+	// Duration is the time until the last row was read.
+	Duration time.Duration
+	// FirstRowTime is the time until the first row was read, or Duration when
+	// there are no rows.
+	FirstRowTime time.Duration
+	Rows         int
+	// Bytes is the size of the column values read, in their text form.
+	Bytes int
+	// Checksum is the hex encoded SHA-256 of the ordered rows, in their text form.
+	Checksum string
+}
@@ -1,7 +1,39 @@
 package model
 
+import (
+	"fmt"
+	"time"
+)
+
+// QueryTaskErr holds a failed query and the time it failed at.
 type QueryTaskErr struct {
 	QueryTaskResult
 	RawQuery string
+	Time     time.Time
 	Err      error
 }
+
+// ParseErr holds an input row that could not be turned into a query.
+type ParseErr struct {
+	Line int
+	Time time.Time
+	Err  error
+}
+
+func (e ParseErr) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// TimeoutError reports a query that did not complete within the query timeout.
+type TimeoutError struct {
+	Timeout time.Duration
+	Err     error
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("query timed out after %v: %v", e.Timeout, e.Err)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return e.Err
+}
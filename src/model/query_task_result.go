@@ -2,8 +2,28 @@ package model
 
 import "time"
 
+// QueryTaskResult holds the latency of a query in the embedded Duration, up to
+// the last row of its result.
 type QueryTaskResult struct {
 	Worker   Worker
 	Hostname string
+	// Duration is measured from the intended start time in an open-loop run, so
+	// it is the sum of the QueueingDelay and the ServiceTime.
 	time.Duration
+	// ServiceTime is the time from the actual start of the query to its last row.
+	ServiceTime time.Duration
+	// QueueingDelay is how late the query started after its intended start time.
+	QueueingDelay time.Duration
+	// FirstRowTime is the time from the start of the query to its first row.
+	FirstRowTime time.Duration
+	Rows         int
+	Bytes        int
+	// Attempts counts the runs of the query, more than one when it was retried.
+	Attempts int
+	// Verified is set when the result was checked against its expected result.
+	Verified bool
+	// End is when the query completed.
+	End time.Time
+	// Warmup is set for the results kept out of the stats of the run.
+	Warmup bool
 }
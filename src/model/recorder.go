@@ -0,0 +1,185 @@
+package model
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// maxRecordedErrs is the number of query and parse errors kept by a Recorder
+// as a sample, the others are only counted.
+const maxRecordedErrs = 100
+
+// Recorder aggregates query task results as they are collected. It keeps
+// histograms for the whole run, per worker and per worker hostname instead of
+// every single result, and counts the errors by class along with a sample of
+// the first maxRecordedErrs of them. Recorder is safe for concurrent use.
+type Recorder struct {
+	mu             sync.Mutex
+	global         *levelRecord
+	service        *Histogram
+	queueing       *Histogram
+	firstRow       *Histogram
+	bytes          int
+	verified       int
+	retried        int
+	retries        int
+	workers        map[Worker]*workerRecord
+	series         *timeSeriesRecord
+	classes        errorClassRecord
+	totalErrs      int
+	mismatches     int
+	timeouts       int
+	errs           []QueryTaskErr
+	totalParseErrs int
+	parseErrs      []ParseErr
+	// closed is set by Close, the results recorded later are ignored.
+	closed bool
+}
+
+// levelRecord aggregates the results of one level of the stats.
+type levelRecord struct {
+	*Histogram
+	rows         int
+	emptyResults int
+}
+
+type workerRecord struct {
+	*levelRecord
+	hostnames map[string]*levelRecord
+}
+
+func NewRecorder() *Recorder {
+	return &Recorder{
+		global:   newLevelRecord(),
+		service:  NewHistogram(),
+		queueing: NewHistogram(),
+		firstRow: NewHistogram(),
+		workers:  make(map[Worker]*workerRecord),
+		classes:  make(errorClassRecord),
+	}
+}
+
+// NewRecorderWithInterval returns a Recorder that also buckets the results and
+// errors into intervals of the run from start by their completion time.
+func NewRecorderWithInterval(start time.Time, interval time.Duration) *Recorder {
+	recorder := NewRecorder()
+	recorder.series = newTimeSeriesRecord(start, interval)
+	return recorder
+}
+
+func (r *Recorder) Record(result QueryTaskResult) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+	r.global.record(result)
+	if r.series != nil {
+		r.series.record(result.End, result.Duration)
+	}
+	r.service.Record(result.ServiceTime)
+	r.queueing.Record(result.QueueingDelay)
+	r.firstRow.Record(result.FirstRowTime)
+	r.bytes += result.Bytes
+	if result.Verified {
+		r.verified++
+	}
+	if result.Attempts > 1 {
+		r.retried++
+		r.retries += result.Attempts - 1
+	}
+
+	worker, exists := r.workers[result.Worker]
+	if !exists {
+		worker = &workerRecord{
+			levelRecord: newLevelRecord(),
+			hostnames:   make(map[string]*levelRecord),
+		}
+		r.workers[result.Worker] = worker
+	}
+	worker.record(result)
+
+	hostname, exists := worker.hostnames[result.Hostname]
+	if !exists {
+		hostname = newLevelRecord()
+		worker.hostnames[result.Hostname] = hostname
+	}
+	hostname.record(result)
+}
+
+func (r *Recorder) RecordErr(err QueryTaskErr) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+	r.totalErrs++
+	r.classes.recordQueryTaskErr(err)
+	var verificationErr *VerificationError
+	if errors.As(err.Err, &verificationErr) {
+		r.mismatches++
+	}
+	var timeoutErr *TimeoutError
+	if errors.As(err.Err, &timeoutErr) {
+		r.timeouts++
+	}
+	if len(r.errs) < maxRecordedErrs {
+		r.errs = append(r.errs, err)
+	}
+	if r.series != nil {
+		r.series.recordErr(err.Time)
+	}
+	if err.Attempts > 1 {
+		r.retries += err.Attempts - 1
+	}
+}
+
+// RecordParseErr records the input row at line that could not be turned into
+// a query. Parse errors are reported as rejected rows, not counted as queries.
+func (r *Recorder) RecordParseErr(line int, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return
+	}
+	parseErr := ParseErr{Line: line, Time: time.Now(), Err: err}
+	r.totalParseErrs++
+	r.classes.recordParseErr(parseErr)
+	if len(r.parseErrs) < maxRecordedErrs {
+		r.parseErrs = append(r.parseErrs, parseErr)
+	}
+}
+
+// Close stops the recording, the results and errors recorded afterwards are
+// ignored. It freezes the stats of a run whose queries may still complete, such
+// as the ones canceled after the grace period of a shutdown.
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.closed = true
+}
+
+func newLevelRecord() *levelRecord {
+	return &levelRecord{
+		Histogram: NewHistogram(),
+	}
+}
+
+func (l *levelRecord) record(result QueryTaskResult) {
+	l.Record(result.Duration)
+	l.rows += result.Rows
+	if result.Rows == 0 {
+		l.emptyResults++
+	}
+}
+
+func (l *levelRecord) merge(other *levelRecord) {
+	l.Merge(other.Histogram)
+	l.rows += other.rows
+	l.emptyResults += other.emptyResults
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxRejectedRows is the number of rejected rows listed in the text output.
+const maxRejectedRows = 20
+
+// RejectedRow is an input row that could not be turned into a query, with the
+// reason it was rejected.
+type RejectedRow struct {
+	Line   int
+	Reason string
+}
+
+// RejectedRowStats counts the rejected input rows. RejectedRows holds the first
+// of them in input order, up to maxRecordedErrs.
+type RejectedRowStats struct {
+	TotalRejectedRows int
+	RejectedRows      []RejectedRow
+}
+
+func newRejectedRowStats(total int, parseErrs []ParseErr) RejectedRowStats {
+	stats := RejectedRowStats{
+		TotalRejectedRows: total,
+		RejectedRows:      make([]RejectedRow, 0, len(parseErrs)),
+	}
+	for _, parseErr := range parseErrs {
+		stats.RejectedRows = append(stats.RejectedRows, RejectedRow{Line: parseErr.Line, Reason: errorMessage(parseErr.Err)})
+	}
+	return stats
+}
+
+// String lists the rejected rows with their line numbers and reasons, up to
+// maxRejectedRows of them.
+func (r RejectedRowStats) String() string {
+	var summary strings.Builder
+	fmt.Fprintf(&summary, "Rejected input rows: %d\n", r.TotalRejectedRows)
+	for _, row := range r.RejectedRows[:min(len(r.RejectedRows), maxRejectedRows)] {
+		fmt.Fprintf(&summary, "  line %d: %s\n", row.Line, row.Reason)
+	}
+	if shown := min(len(r.RejectedRows), maxRejectedRows); r.TotalRejectedRows > shown {
+		fmt.Fprintf(&summary, "  ... (%d more)\n", r.TotalRejectedRows-shown)
+	}
+	return summary.String()
+}
@@ -2,23 +2,45 @@ package model
 
 import (
 	"fmt"
-	"sort"
+	"strings"
 	"time"
 )
 
 type Stats struct {
 	queryStats
+	RunTimeStats
+	ResultStats
+	QueueingStats
+	RetryStats
 	QueryErrorStats
+	RejectedRowStats
+	QueryHostnameStats map[string]*queryStats
+	// Partial is set when the run was interrupted before processing all the input.
+	Partial bool
+	// Warmup holds the stats of the warm-up when they are reported.
+	Warmup *Stats
+	// Utilization holds how busy every worker of the pool was, warm-up included.
+	Utilization []WorkerUtilization
+	// TimeSeries holds the stats of every interval when the results are recorded by interval.
+	TimeSeries  *TimeSeries
+	percentiles []float64
 }
 
 type queryStats struct {
-	TotalSuccess        int
+	TotalSuccess int
+	// TotalProcessingTime is the sum of the query times, which grows with the
+	// number of workers, unlike the elapsed time of the run.
 	TotalProcessingTime time.Duration
-	MinQueryTime        time.Duration
-	MedianQueryTime     time.Duration
-	AvgQueryTime        time.Duration
-	MaxQueryTime        time.Duration
-	QueryWorkerStats    map[Worker]*queryWorkerStats
+	// Throughput is the number of successful queries per second of elapsed time.
+	Throughput       float64
+	MinQueryTime     time.Duration
+	MedianQueryTime  time.Duration
+	AvgQueryTime     time.Duration
+	MaxQueryTime     time.Duration
+	Percentiles      []Percentile
+	TotalRows        int
+	EmptyResults     int
+	QueryWorkerStats map[Worker]*queryWorkerStats
 }
 
 type queryWorkerStats struct {
@@ -26,115 +48,309 @@ type queryWorkerStats struct {
 	QueryHostnameStats map[string]*queryStats
 }
 
+// RunTimeStats holds the wall-clock start and end of the measured part of the run.
+type RunTimeStats struct {
+	StartTime time.Time
+	EndTime   time.Time
+	Elapsed   time.Duration
+}
+
+// ResultStats describes the result sets read by the successful queries.
+type ResultStats struct {
+	TotalVerified   int
+	TotalBytes      int
+	AvgFirstRowTime time.Duration
+	MaxFirstRowTime time.Duration
+}
+
+// QueueingStats splits the latency of an open-loop run into the time queries
+// waited to start and the time the database took to serve them.
+type QueueingStats struct {
+	AvgServiceTime   time.Duration
+	MaxServiceTime   time.Duration
+	AvgQueueingDelay time.Duration
+	MaxQueueingDelay time.Duration
+}
+
+// RetryStats tells apart the queries that succeeded on the first try from the
+// ones that needed retries. TotalRetries also counts the retries of failed queries.
+type RetryStats struct {
+	FirstTrySuccess int
+	RetriedSuccess  int
+	TotalRetries    int
+}
+
 type QueryErrorStats struct {
-	TotalErrs     int
+	TotalErrs int
+	// TotalMismatches counts the errors of queries that returned an unexpected result.
+	TotalMismatches int
+	// TotalTimeouts counts the errors of queries that exceeded the query timeout.
+	TotalTimeouts int
+	// QueryTaskErrs holds the first failed queries, up to maxRecordedErrs.
 	QueryTaskErrs []QueryTaskErr
+	// ErrorClasses groups the query errors and the input parse errors by class.
+	ErrorClasses []ErrorClassStats
+}
+
+// NewStats returns Stats that also calculate the given query time percentiles.
+func NewStats(percentiles []float64) Stats {
+	return Stats{percentiles: percentiles}
 }
 
 func (qs Stats) String() string {
-	return fmt.Sprintf(
-		"\nSTATS\n"+
+	title := "STATS"
+	if qs.Partial {
+		title = "PARTIAL STATS (interrupted run)"
+	}
+	summary := qs.summary(title)
+	if len(qs.Utilization) > 0 {
+		summary += utilizationTable(qs.Utilization)
+	}
+	if qs.TimeSeries != nil && len(qs.TimeSeries.Intervals) > 0 {
+		summary += qs.TimeSeries.String()
+	}
+	if qs.Warmup != nil {
+		summary += qs.Warmup.summary("WARM-UP STATS")
+	}
+	return summary
+}
+
+func (qs Stats) summary(title string) string {
+	var details strings.Builder
+	for _, percentile := range qs.Percentiles {
+		fmt.Fprintf(&details, "%s query time: %v\n", percentile.Label(), percentile.QueryTime)
+	}
+	fmt.Fprintf(&details,
+		"Total rows retrieved: %d\n"+
+			"Total bytes retrieved: %d\n"+
+			"Queries with empty results: %d\n"+
+			"Average time to first row: %v\n"+
+			"Maximum time to first row: %v\n",
+		qs.TotalRows,
+		qs.TotalBytes,
+		qs.EmptyResults,
+		qs.AvgFirstRowTime,
+		qs.MaxFirstRowTime,
+	)
+	// Results are only verified when there are expected results.
+	if qs.TotalVerified+qs.TotalMismatches > 0 {
+		fmt.Fprintf(&details,
+			"Verified results: %d\n"+
+				"Result mismatches: %d\n",
+			qs.TotalVerified,
+			qs.TotalMismatches,
+		)
+	}
+	// Queries are only retried with a retry policy.
+	if qs.TotalRetries > 0 {
+		fmt.Fprintf(&details,
+			"Successful on first try: %d\n"+
+				"Successful after retries: %d\n"+
+				"Total retries: %d\n",
+			qs.FirstTrySuccess,
+			qs.RetriedSuccess,
+			qs.TotalRetries,
+		)
+	}
+	// Queries only wait to start in an open-loop run.
+	if qs.MaxQueueingDelay > 0 {
+		fmt.Fprintf(&details,
+			"Average service time: %v\n"+
+				"Maximum service time: %v\n"+
+				"Average queueing delay: %v\n"+
+				"Maximum queueing delay: %v\n",
+			qs.AvgServiceTime,
+			qs.MaxServiceTime,
+			qs.AvgQueueingDelay,
+			qs.MaxQueueingDelay,
+		)
+	}
+
+	var runTime string
+	if !qs.StartTime.IsZero() {
+		runTime = fmt.Sprintf(
+			"Start time: %s\n"+
+				"End time: %s\n"+
+				"Elapsed time: %v\n"+
+				"Throughput: %.1f queries/s\n",
+			qs.StartTime.Format(time.RFC3339Nano),
+			qs.EndTime.Format(time.RFC3339Nano),
+			qs.Elapsed,
+			qs.Throughput,
+		)
+	}
+
+	summary := fmt.Sprintf(
+		"\n%s\n"+
 			"\nTotal Queries: %d"+
 			"\nNumber of queries successfully processed: %d\n"+
-			"Total processing time: %v\n"+
+			"%s"+
+			"Total query time (sum of all queries): %v\n"+
 			"Minimum query time: %v\n"+
 			"Median query time: %v\n"+
 			"Average query time: %v\n"+
 			"Maximum query time: %v\n"+
-			"Total Errors: %d\n",
+			"%s"+
+			"Total Errors: %d\n"+
+			"Query timeouts: %d\n",
+		title,
 		qs.TotalSuccess+qs.TotalErrs,
 		qs.TotalSuccess,
+		runTime,
 		qs.TotalProcessingTime,
 		qs.MinQueryTime,
 		qs.MedianQueryTime,
 		qs.AvgQueryTime,
 		qs.MaxQueryTime,
+		details.String(),
 		qs.TotalErrs,
+		qs.TotalTimeouts,
 	)
+	if qs.TotalRejectedRows > 0 {
+		summary += qs.RejectedRowStats.String()
+	}
+	if len(qs.ErrorClasses) > 0 {
+		summary += errorClassesSummary(qs.ErrorClasses)
+	}
+	return summary
 }
 
+// CalculateStats records all the query task results and calculates their stats.
 func (qs *Stats) CalculateStats(queryTaskResults []QueryTaskResult, queryTaskErrs []QueryTaskErr) {
-	qs.TotalSuccess = len(queryTaskResults)
-	qs.TotalErrs = len(queryTaskErrs)
-	qs.QueryTaskErrs = queryTaskErrs
+	recorder := NewRecorder()
+	for _, result := range queryTaskResults {
+		recorder.Record(result)
+	}
+	for _, err := range queryTaskErrs {
+		recorder.RecordErr(err)
+	}
+	qs.CalculateRecordedStats(recorder)
+}
 
-	if qs.TotalSuccess == 0 {
-		return
+// CalculateRecordedStats calculates the stats of the results collected by the recorder.
+func (qs *Stats) CalculateRecordedStats(recorder *Recorder) {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+
+	qs.TotalErrs = recorder.totalErrs
+	qs.TotalMismatches = recorder.mismatches
+	qs.TotalTimeouts = recorder.timeouts
+	qs.QueryTaskErrs = recorder.errs
+	qs.RetryStats = RetryStats{
+		FirstTrySuccess: recorder.global.Count() - recorder.retried,
+		RetriedSuccess:  recorder.retried,
+		TotalRetries:    recorder.retries,
+	}
+	qs.ErrorClasses = recorder.classes.calculate()
+	if recorder.series != nil {
+		qs.TimeSeries = recorder.series.calculate(qs.percentiles)
 	}
-	queryTimes := make([]time.Duration, 0, qs.TotalSuccess)
-	queryWorkerTimes := make(map[Worker][]time.Duration)
-	queryHostnameTimes := make(map[Worker]map[string][]time.Duration)
+	qs.RejectedRowStats = newRejectedRowStats(recorder.totalParseErrs, recorder.parseErrs)
 
-	for _, result := range queryTaskResults {
-		queryTimes = append(queryTimes, result.Duration)
+	if recorder.global.Count() == 0 {
+		return
+	}
+	qs.calculateAllStats(recorder, qs.percentiles)
+	qs.calculateHostnameStats(recorder)
+	qs.QueueingStats = QueueingStats{
+		AvgServiceTime:   recorder.service.Mean(),
+		MaxServiceTime:   recorder.service.Max(),
+		AvgQueueingDelay: recorder.queueing.Mean(),
+		MaxQueueingDelay: recorder.queueing.Max(),
+	}
+	qs.ResultStats = ResultStats{
+		TotalVerified:   recorder.verified,
+		TotalBytes:      recorder.bytes,
+		AvgFirstRowTime: recorder.firstRow.Mean(),
+		MaxFirstRowTime: recorder.firstRow.Max(),
+	}
+}
 
-		if _, exists := queryWorkerTimes[result.Worker]; !exists {
-			queryWorkerTimes[result.Worker] = []time.Duration{}
+// SetRunTime sets the wall-clock window of the run and the throughput of the
+// run, of every worker and of every hostname over it.
+func (qs *Stats) SetRunTime(start time.Time, end time.Time) {
+	qs.RunTimeStats = RunTimeStats{StartTime: start, EndTime: end, Elapsed: end.Sub(start)}
+	qs.queryStats.calculateThroughput(qs.Elapsed)
+	for _, workerStats := range qs.QueryWorkerStats {
+		workerStats.calculateThroughput(qs.Elapsed)
+		for _, hostnameStats := range workerStats.QueryHostnameStats {
+			hostnameStats.calculateThroughput(qs.Elapsed)
 		}
-		queryWorkerTimes[result.Worker] = append(queryWorkerTimes[result.Worker], result.Duration)
+	}
+	for _, hostnameStats := range qs.QueryHostnameStats {
+		hostnameStats.calculateThroughput(qs.Elapsed)
+	}
+	if qs.TimeSeries != nil {
+		qs.TimeSeries.setRunTime(start, end)
+	}
+}
 
-		if _, exists := queryHostnameTimes[result.Worker]; !exists {
-			queryHostnameTimes[result.Worker] = make(map[string][]time.Duration)
-		}
-		if _, exists := queryHostnameTimes[result.Worker][result.Hostname]; !exists {
-			queryHostnameTimes[result.Worker][result.Hostname] = []time.Duration{}
+func (qs *queryStats) calculateThroughput(elapsed time.Duration) {
+	qs.Throughput = 0
+	if elapsed > 0 {
+		qs.Throughput = float64(qs.TotalSuccess) / elapsed.Seconds()
+	}
+}
+
+// calculateHostnameStats rolls up the hostname stats across all the workers.
+func (qs *Stats) calculateHostnameStats(recorder *Recorder) {
+	hostnameRecords := make(map[string]*levelRecord)
+	for _, workerRecord := range recorder.workers {
+		for hostname, record := range workerRecord.hostnames {
+			if _, exists := hostnameRecords[hostname]; !exists {
+				hostnameRecords[hostname] = newLevelRecord()
+			}
+			hostnameRecords[hostname].merge(record)
 		}
-		queryHostnameTimes[result.Worker][result.Hostname] = append(queryHostnameTimes[result.Worker][result.Hostname], result.Duration)
 	}
 
-	qs.calculateAllStats(queryTimes, queryWorkerTimes, queryHostnameTimes)
+	qs.QueryHostnameStats = make(map[string]*queryStats, len(hostnameRecords))
+	for hostname, record := range hostnameRecords {
+		hostnameStats := queryStats{}
+		hostnameStats.calculateStats(record, qs.percentiles)
+		qs.QueryHostnameStats[hostname] = &hostnameStats
+	}
 }
 
-func (qs *queryStats) calculateAllStats(queryTimes []time.Duration, queryWorkerTimes map[Worker][]time.Duration, queryHostnameTimes map[Worker]map[string][]time.Duration) {
-	qs.calculateStats(queryTimes)
+func (qs *queryStats) calculateAllStats(recorder *Recorder, percentiles []float64) {
+	qs.calculateStats(recorder.global, percentiles)
 	qs.QueryWorkerStats = make(map[Worker]*queryWorkerStats)
 
-	for worker, queryWorkerTime := range queryWorkerTimes {
+	for worker, workerRecord := range recorder.workers {
 		workerStats := queryWorkerStats{}
-		workerStats.calculateStats(queryWorkerTime)
+		workerStats.calculateStats(workerRecord.levelRecord, percentiles)
 		qs.QueryWorkerStats[worker] = &workerStats
 		qs.QueryWorkerStats[worker].QueryHostnameStats = make(map[string]*queryStats)
 
-		for hostname, queryHostnameTime := range queryHostnameTimes[worker] {
+		for hostname, hostnameRecord := range workerRecord.hostnames {
 			hostnameStats := queryStats{}
-			hostnameStats.calculateStats(queryHostnameTime)
+			hostnameStats.calculateStats(hostnameRecord, percentiles)
 			qs.QueryWorkerStats[worker].QueryHostnameStats[hostname] = &hostnameStats
 		}
 
 	}
 }
 
-func (qs *queryStats) calculateStats(queryTimes []time.Duration) {
-	totalQueries := len(queryTimes)
-	if totalQueries == 0 {
+func (qs *queryStats) calculateStats(record *levelRecord, percentiles []float64) {
+	if record.Count() == 0 {
 		return
 	}
 
-	var totalProcessingTime time.Duration
-	minQueryTime := queryTimes[0]
-	maxQueryTime := queryTimes[0]
+	values := record.valuesAtPercentiles(percentiles)
 
-	for _, qt := range queryTimes {
-		totalProcessingTime += qt
-		if qt < minQueryTime {
-			minQueryTime = qt
-		}
-		if qt > maxQueryTime {
-			maxQueryTime = qt
-		}
-	}
-
-	avgQueryTime := totalProcessingTime / time.Duration(totalQueries)
-
-	sort.Slice(queryTimes, func(i, j int) bool { return queryTimes[i] < queryTimes[j] })
-	medianQueryTime := queryTimes[totalQueries/2]
-	if totalQueries%2 == 0 {
-		medianQueryTime = (queryTimes[totalQueries/2-1] + queryTimes[totalQueries/2]) / 2
+	qs.TotalSuccess = record.Count()
+	qs.TotalProcessingTime = record.Sum()
+	qs.MinQueryTime = record.Min()
+	qs.MedianQueryTime = record.Median()
+	qs.AvgQueryTime = record.Mean()
+	qs.MaxQueryTime = record.Max()
+	qs.TotalRows = record.rows
+	qs.EmptyResults = record.emptyResults
+	qs.Percentiles = nil
+	for i, percentile := range percentiles {
+		qs.Percentiles = append(qs.Percentiles, Percentile{
+			Percentile: percentile,
+			QueryTime:  values[i],
+		})
 	}
-
-	qs.TotalProcessingTime = totalProcessingTime
-	qs.MinQueryTime = minQueryTime
-	qs.MedianQueryTime = medianQueryTime
-	qs.AvgQueryTime = avgQueryTime
-	qs.MaxQueryTime = maxQueryTime
 }
@@ -0,0 +1,216 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// durationJSON is the JSON form of a duration, in nanoseconds and human readable.
+type durationJSON struct {
+	Nanoseconds int64  `json:"ns"`
+	Human       string `json:"human"`
+}
+
+type percentileJSON struct {
+	Percentile float64      `json:"percentile"`
+	QueryTime  durationJSON `json:"query_time"`
+}
+
+type queryStatsJSON struct {
+	TotalSuccess        int                              `json:"total_success"`
+	TotalProcessingTime durationJSON                     `json:"total_processing_time"`
+	Throughput          float64                          `json:"throughput"`
+	MinQueryTime        durationJSON                     `json:"min_query_time"`
+	MedianQueryTime     durationJSON                     `json:"median_query_time"`
+	AvgQueryTime        durationJSON                     `json:"avg_query_time"`
+	MaxQueryTime        durationJSON                     `json:"max_query_time"`
+	Percentiles         []percentileJSON                 `json:"percentiles,omitempty"`
+	TotalRows           int                              `json:"total_rows"`
+	EmptyResults        int                              `json:"empty_results"`
+	QueryWorkerStats    map[Worker]*queryWorkerStatsJSON `json:"worker_stats,omitempty"`
+}
+
+type queryWorkerStatsJSON struct {
+	queryStatsJSON
+	QueryHostnameStats map[string]*queryStatsJSON `json:"hostname_stats,omitempty"`
+}
+
+type queryTaskErrJSON struct {
+	Worker    Worker       `json:"worker"`
+	Hostname  string       `json:"hostname"`
+	QueryTime durationJSON `json:"query_time"`
+	RawQuery  string       `json:"raw_query"`
+	Class     string       `json:"class"`
+	Err       string       `json:"error"`
+}
+
+type errorClassJSON struct {
+	Class        string         `json:"class"`
+	Count        int            `json:"count"`
+	FirstTime    time.Time      `json:"first_occurrence"`
+	FirstErr     string         `json:"first_error"`
+	ExampleQuery string         `json:"example_query,omitempty"`
+	Workers      map[Worker]int `json:"workers,omitempty"`
+	Hostnames    map[string]int `json:"hostnames,omitempty"`
+}
+
+type rejectedRowJSON struct {
+	Line   int    `json:"line"`
+	Reason string `json:"reason"`
+}
+
+type workerUtilizationJSON struct {
+	Worker      Worker       `json:"worker"`
+	Tasks       int          `json:"tasks"`
+	Stolen      int          `json:"stolen"`
+	BusyTime    durationJSON `json:"busy_time"`
+	Utilization float64      `json:"utilization"`
+}
+
+type statsJSON struct {
+	Partial      bool `json:"partial"`
+	TotalQueries int  `json:"total_queries"`
+	queryStatsJSON
+	StartTime          time.Time                  `json:"start_time"`
+	EndTime            time.Time                  `json:"end_time"`
+	Elapsed            durationJSON               `json:"elapsed"`
+	QueryHostnameStats map[string]*queryStatsJSON `json:"hostname_stats,omitempty"`
+	TotalVerified      int                        `json:"total_verified"`
+	TotalBytes         int                        `json:"total_bytes"`
+	AvgFirstRowTime    durationJSON               `json:"avg_first_row_time"`
+	MaxFirstRowTime    durationJSON               `json:"max_first_row_time"`
+	AvgServiceTime     durationJSON               `json:"avg_service_time"`
+	MaxServiceTime     durationJSON               `json:"max_service_time"`
+	AvgQueueingDelay   durationJSON               `json:"avg_queueing_delay"`
+	MaxQueueingDelay   durationJSON               `json:"max_queueing_delay"`
+	FirstTrySuccess    int                        `json:"first_try_success"`
+	RetriedSuccess     int                        `json:"retried_success"`
+	TotalRetries       int                        `json:"total_retries"`
+	TotalErrs          int                        `json:"total_errors"`
+	TotalMismatches    int                        `json:"total_mismatches"`
+	TotalTimeouts      int                        `json:"total_timeouts"`
+	TotalRejectedRows  int                        `json:"total_rejected_rows"`
+	RejectedRows       []rejectedRowJSON          `json:"rejected_rows"`
+	ErrorClasses       []errorClassJSON           `json:"error_classes"`
+	QueryTaskErrs      []queryTaskErrJSON         `json:"errors"`
+	Utilization        []workerUtilizationJSON    `json:"worker_utilization,omitempty"`
+	TimeSeries         *TimeSeries                `json:"time_series,omitempty"`
+	Warmup             *Stats                     `json:"warmup,omitempty"`
+}
+
+func (qs Stats) MarshalJSON() ([]byte, error) {
+	stats := statsJSON{
+		Partial:           qs.Partial,
+		TotalQueries:      qs.TotalSuccess + qs.TotalErrs,
+		queryStatsJSON:    qs.queryStats.toJSON(),
+		StartTime:         qs.StartTime,
+		EndTime:           qs.EndTime,
+		Elapsed:           newDurationJSON(qs.Elapsed),
+		TotalVerified:     qs.TotalVerified,
+		TotalBytes:        qs.TotalBytes,
+		AvgFirstRowTime:   newDurationJSON(qs.AvgFirstRowTime),
+		MaxFirstRowTime:   newDurationJSON(qs.MaxFirstRowTime),
+		AvgServiceTime:    newDurationJSON(qs.AvgServiceTime),
+		MaxServiceTime:    newDurationJSON(qs.MaxServiceTime),
+		AvgQueueingDelay:  newDurationJSON(qs.AvgQueueingDelay),
+		MaxQueueingDelay:  newDurationJSON(qs.MaxQueueingDelay),
+		FirstTrySuccess:   qs.FirstTrySuccess,
+		RetriedSuccess:    qs.RetriedSuccess,
+		TotalRetries:      qs.TotalRetries,
+		TotalErrs:         qs.TotalErrs,
+		TotalMismatches:   qs.TotalMismatches,
+		TotalTimeouts:     qs.TotalTimeouts,
+		TotalRejectedRows: qs.TotalRejectedRows,
+		RejectedRows:      make([]rejectedRowJSON, 0, len(qs.RejectedRows)),
+		ErrorClasses:      make([]errorClassJSON, 0, len(qs.ErrorClasses)),
+		QueryTaskErrs:     make([]queryTaskErrJSON, 0, len(qs.QueryTaskErrs)),
+		TimeSeries:        qs.TimeSeries,
+		Warmup:            qs.Warmup,
+	}
+
+	if len(qs.QueryWorkerStats) > 0 {
+		stats.QueryWorkerStats = make(map[Worker]*queryWorkerStatsJSON, len(qs.QueryWorkerStats))
+	}
+	for worker, workerStats := range qs.QueryWorkerStats {
+		workerJSON := &queryWorkerStatsJSON{
+			queryStatsJSON:     workerStats.queryStats.toJSON(),
+			QueryHostnameStats: make(map[string]*queryStatsJSON, len(workerStats.QueryHostnameStats)),
+		}
+		for hostname, hostnameStats := range workerStats.QueryHostnameStats {
+			hostnameJSON := hostnameStats.toJSON()
+			workerJSON.QueryHostnameStats[hostname] = &hostnameJSON
+		}
+		stats.QueryWorkerStats[worker] = workerJSON
+	}
+
+	if len(qs.QueryHostnameStats) > 0 {
+		stats.QueryHostnameStats = make(map[string]*queryStatsJSON, len(qs.QueryHostnameStats))
+	}
+	for hostname, hostnameStats := range qs.QueryHostnameStats {
+		hostnameJSON := hostnameStats.toJSON()
+		stats.QueryHostnameStats[hostname] = &hostnameJSON
+	}
+
+	for _, worker := range qs.Utilization {
+		stats.Utilization = append(stats.Utilization, workerUtilizationJSON{
+			Worker:      worker.Worker,
+			Tasks:       worker.Tasks,
+			Stolen:      worker.Stolen,
+			BusyTime:    newDurationJSON(worker.BusyTime),
+			Utilization: worker.Utilization,
+		})
+	}
+
+	for _, rejectedRow := range qs.RejectedRows {
+		stats.RejectedRows = append(stats.RejectedRows, rejectedRowJSON(rejectedRow))
+	}
+
+	for _, errorClass := range qs.ErrorClasses {
+		stats.ErrorClasses = append(stats.ErrorClasses, errorClassJSON(errorClass))
+	}
+
+	for _, queryTaskErr := range qs.QueryTaskErrs {
+		errJSON := queryTaskErrJSON{
+			Worker:    queryTaskErr.Worker,
+			Hostname:  queryTaskErr.Hostname,
+			QueryTime: newDurationJSON(queryTaskErr.Duration),
+			RawQuery:  queryTaskErr.RawQuery,
+			Class:     ClassifyError(queryTaskErr.Err),
+		}
+		if queryTaskErr.Err != nil {
+			errJSON.Err = queryTaskErr.Err.Error()
+		}
+		stats.QueryTaskErrs = append(stats.QueryTaskErrs, errJSON)
+	}
+
+	return json.Marshal(stats)
+}
+
+// toJSON converts the stats of a single level, without the worker breakdown.
+func (qs *queryStats) toJSON() queryStatsJSON {
+	stats := queryStatsJSON{
+		TotalSuccess:        qs.TotalSuccess,
+		TotalProcessingTime: newDurationJSON(qs.TotalProcessingTime),
+		Throughput:          qs.Throughput,
+		MinQueryTime:        newDurationJSON(qs.MinQueryTime),
+		MedianQueryTime:     newDurationJSON(qs.MedianQueryTime),
+		AvgQueryTime:        newDurationJSON(qs.AvgQueryTime),
+		MaxQueryTime:        newDurationJSON(qs.MaxQueryTime),
+		TotalRows:           qs.TotalRows,
+		EmptyResults:        qs.EmptyResults,
+	}
+	for _, percentile := range qs.Percentiles {
+		stats.Percentiles = append(stats.Percentiles, percentileJSON{
+			Percentile: percentile.Percentile,
+			QueryTime:  newDurationJSON(percentile.QueryTime),
+		})
+	}
+	return stats
+}
+
+func newDurationJSON(duration time.Duration) durationJSON {
+	return durationJSON{
+		Nanoseconds: int64(duration),
+		Human:       duration.String(),
+	}
+}
@@ -1,9 +1,15 @@
 package model
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"reflect"
 	"testing"
 	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 func TestCalculateStats(t *testing.T) {
@@ -174,11 +180,424 @@ func TestCalculateStats(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			qs := Stats{}
-			qs.QueryWorkerStats = make(map[Worker]*queryWorkerStats)
 			qs.CalculateStats(tt.queryTaskResults, nil)
-			if !reflect.DeepEqual(qs, tt.expectedQueryStats) {
-				t.Errorf("CalculateStats() = %+v, want %+v", qs, tt.expectedQueryStats)
+			assertQueryStats(t, tt.expectedQueryStats, qs.queryStats)
+		})
+	}
+}
+
+func TestCalculateStatsPercentiles(t *testing.T) {
+	var queryTaskResults []QueryTaskResult
+	for i := 1; i <= 1000; i++ {
+		worker := Worker(i%2 + 1)
+		queryTaskResults = append(queryTaskResults, QueryTaskResult{Worker: worker, Hostname: "host1", Duration: time.Duration(i) * time.Millisecond})
+	}
+
+	qs := NewStats([]float64{50, 90, 99, 99.9, 100})
+	qs.CalculateStats(queryTaskResults, nil)
+
+	expected := []Percentile{
+		{Percentile: 50, QueryTime: 500 * time.Millisecond},
+		{Percentile: 90, QueryTime: 900 * time.Millisecond},
+		{Percentile: 99, QueryTime: 990 * time.Millisecond},
+		{Percentile: 99.9, QueryTime: 999 * time.Millisecond},
+		{Percentile: 100, QueryTime: 1000 * time.Millisecond},
+	}
+	assertPercentiles(t, expected, qs.Percentiles)
+
+	// Worker 1 gets the even durations and worker 2 the odd ones.
+	assertQueryTime(t, 500*time.Millisecond, qs.QueryWorkerStats[1].Percentiles[0].QueryTime)
+	assertQueryTime(t, 499*time.Millisecond, qs.QueryWorkerStats[2].QueryHostnameStats["host1"].Percentiles[0].QueryTime)
+}
+
+func TestCalculateRecordedStats(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.Record(QueryTaskResult{Worker: 1, Hostname: "host1", Duration: 2 * time.Second})
+	recorder.RecordErr(QueryTaskErr{QueryTaskResult: QueryTaskResult{Worker: 2, Hostname: "host2"}})
+
+	qs := Stats{}
+	qs.CalculateRecordedStats(recorder)
+
+	assert.Equal(t, 1, qs.TotalSuccess)
+	assert.Equal(t, 1, qs.TotalErrs)
+	assert.Len(t, qs.QueryWorkerStats, 1)
+}
+
+// assertQueryStats compares query stats allowing the histogram precision on the
+// median and the percentiles.
+func assertQueryStats(t *testing.T, expected, actual queryStats) {
+	t.Helper()
+	assert.Equal(t, expected.TotalSuccess, actual.TotalSuccess)
+	assert.Equal(t, expected.TotalProcessingTime, actual.TotalProcessingTime)
+	assert.Equal(t, expected.MinQueryTime, actual.MinQueryTime)
+	assertQueryTime(t, expected.MedianQueryTime, actual.MedianQueryTime)
+	assert.Equal(t, expected.AvgQueryTime, actual.AvgQueryTime)
+	assert.Equal(t, expected.MaxQueryTime, actual.MaxQueryTime)
+	assertPercentiles(t, expected.Percentiles, actual.Percentiles)
+
+	assert.Len(t, actual.QueryWorkerStats, len(expected.QueryWorkerStats))
+	for worker, expectedWorkerStats := range expected.QueryWorkerStats {
+		actualWorkerStats, exists := actual.QueryWorkerStats[worker]
+		if !assert.True(t, exists, "missing worker %d", worker) {
+			continue
+		}
+		assertQueryStats(t, expectedWorkerStats.queryStats, actualWorkerStats.queryStats)
+
+		assert.Len(t, actualWorkerStats.QueryHostnameStats, len(expectedWorkerStats.QueryHostnameStats))
+		for hostname, expectedHostnameStats := range expectedWorkerStats.QueryHostnameStats {
+			actualHostnameStats, exists := actualWorkerStats.QueryHostnameStats[hostname]
+			if !assert.True(t, exists, "missing hostname %s", hostname) {
+				continue
+			}
+			assertQueryStats(t, *expectedHostnameStats, *actualHostnameStats)
+		}
+	}
+}
+
+func assertPercentiles(t *testing.T, expected, actual []Percentile) {
+	t.Helper()
+	if !assert.Len(t, actual, len(expected)) {
+		return
+	}
+	for i := range expected {
+		assert.Equal(t, expected[i].Percentile, actual[i].Percentile)
+		assertQueryTime(t, expected[i].QueryTime, actual[i].QueryTime)
+	}
+}
+
+func assertQueryTime(t *testing.T, expected, actual time.Duration) {
+	t.Helper()
+	assert.InEpsilon(t, float64(expected), float64(actual), 1.0/subBucketHalfCount)
+}
+
+func TestParsePercentiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []float64
+		wantErr bool
+	}{
+		{name: "Valid List", value: "50,90,99,99.9", want: []float64{50, 90, 99, 99.9}},
+		{name: "Unsorted With Duplicates", value: "99, 50,99", want: []float64{50, 99}},
+		{name: "Not A Number", value: "50,p99", wantErr: true},
+		{name: "Out Of Range", value: "0,50", wantErr: true},
+		{name: "Above 100", value: "100.1", wantErr: true},
+		{name: "NaN", value: "50,NaN", wantErr: true},
+		{name: "Inf", value: "Inf", wantErr: true},
+		{name: "Negative Inf", value: "-Inf,99", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParsePercentiles(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParsePercentiles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePercentiles() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+func TestCalculateStatsRows(t *testing.T) {
+	qs := Stats{}
+	qs.CalculateStats([]QueryTaskResult{
+		{Worker: 1, Hostname: "host1", Duration: 4 * time.Millisecond, FirstRowTime: 2 * time.Millisecond, Rows: 60, Bytes: 3000},
+		{Worker: 1, Hostname: "host2", Duration: 2 * time.Millisecond, FirstRowTime: 2 * time.Millisecond},
+		{Worker: 2, Hostname: "host1", Duration: 3 * time.Millisecond, FirstRowTime: 1 * time.Millisecond, Rows: 30, Bytes: 1500},
+	}, nil)
+
+	assert.Equal(t, 90, qs.TotalRows)
+	assert.Equal(t, 1, qs.EmptyResults)
+	assert.Equal(t, 4500, qs.TotalBytes)
+	assert.Equal(t, 5*time.Millisecond/3, qs.AvgFirstRowTime)
+	assert.Equal(t, 2*time.Millisecond, qs.MaxFirstRowTime)
+	assert.Equal(t, 60, qs.QueryWorkerStats[1].TotalRows)
+	assert.Equal(t, 1, qs.QueryWorkerStats[1].QueryHostnameStats["host2"].EmptyResults)
+	assert.Equal(t, 90, qs.QueryHostnameStats["host1"].TotalRows)
+	assert.Contains(t, qs.String(), "Queries with empty results: 1")
+}
+
+func TestStats_MarshalJSON(t *testing.T) {
+	qs := NewStats([]float64{99})
+	qs.CalculateStats(
+		[]QueryTaskResult{{Worker: 1, Hostname: "host1", Duration: 2 * time.Millisecond}},
+		[]QueryTaskErr{{QueryTaskResult: QueryTaskResult{Worker: 2, Hostname: "host2", Duration: time.Millisecond}, RawQuery: "SELECT 1", Err: errors.New("connection refused")}},
+	)
+
+	data, err := json.Marshal(qs)
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var report map[string]any
+	if !assert.NoError(t, json.Unmarshal(data, &report)) {
+		return
+	}
+	assert.EqualValues(t, 2, report["total_queries"])
+	assert.EqualValues(t, 1, report["total_success"])
+	assert.Equal(t, map[string]any{"ns": float64(2000000), "human": "2ms"}, report["max_query_time"])
+
+	workerStats := report["worker_stats"].(map[string]any)["1"].(map[string]any)
+	assert.Contains(t, workerStats["hostname_stats"], "host1")
+	assert.Len(t, workerStats["percentiles"], 1)
+
+	errs := report["errors"].([]any)
+	if assert.Len(t, errs, 1) {
+		assert.Equal(t, "connection refused", errs[0].(map[string]any)["error"])
+	}
+}
+
+func TestCalculateStatsHostnameRollup(t *testing.T) {
+	qs := Stats{}
+	qs.CalculateStats([]QueryTaskResult{
+		{Worker: 1, Hostname: "host1", Duration: 2 * time.Second},
+		{Worker: 2, Hostname: "host1", Duration: 4 * time.Second},
+		{Worker: 2, Hostname: "host2", Duration: 1 * time.Second},
+	}, nil)
+
+	assert.Len(t, qs.QueryHostnameStats, 2)
+	assertQueryStats(t, queryStats{
+		TotalSuccess:        2,
+		TotalProcessingTime: 6 * time.Second,
+		MinQueryTime:        2 * time.Second,
+		MedianQueryTime:     3 * time.Second,
+		AvgQueryTime:        3 * time.Second,
+		MaxQueryTime:        4 * time.Second,
+	}, *qs.QueryHostnameStats["host1"])
+}
+
+func TestStats_Breakdown(t *testing.T) {
+	qs := NewStats([]float64{99})
+	qs.CalculateStats([]QueryTaskResult{
+		{Worker: 2, Hostname: "host_b", Duration: 4 * time.Millisecond, Rows: 3},
+		{Worker: 10, Hostname: "host_a", Duration: 1 * time.Millisecond},
+		{Worker: 1, Hostname: "host_b", Duration: 2 * time.Millisecond, Rows: 5},
+	}, nil)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	qs.SetRunTime(start, start.Add(2*time.Second))
+
+	workerTable := qs.Breakdown(BreakdownWorker)
+	assert.Contains(t, workerTable, "WORKER STATS")
+	assert.Contains(t, workerTable, "p99")
+	assert.Regexp(t, `(?s)\n\s+1\s+1\s+0\.5\s+5\s+0\s+2ms.*\n\s+2\s+1\s+0\.5\s+3\s+0\s+4ms.*\n\s+10\s+1\s+0\.5\s+0\s+1\s+1ms`, workerTable)
+
+	hostnameTable := qs.Breakdown(BreakdownHostname)
+	assert.Contains(t, hostnameTable, "HOSTNAME STATS")
+	assert.Regexp(t, `(?s)host_a\s+1\s+0\.5\s+0\s+1\s+1ms.*host_b\s+2\s+1\.0\s+8\s+0\s+2ms`, hostnameTable)
+}
+
+func TestStats_SetRunTime(t *testing.T) {
+	qs := NewStats(nil)
+	qs.CalculateStats([]QueryTaskResult{
+		{Worker: 1, Hostname: "host1", Duration: 300 * time.Millisecond},
+		{Worker: 1, Hostname: "host1", Duration: 300 * time.Millisecond},
+		{Worker: 2, Hostname: "host2", Duration: 300 * time.Millisecond},
+		{Worker: 2, Hostname: "host1", Duration: 300 * time.Millisecond},
+	}, nil)
+	assert.NotContains(t, qs.String(), "Elapsed time")
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	qs.SetRunTime(start, start.Add(400*time.Millisecond))
+	assert.Equal(t, 400*time.Millisecond, qs.Elapsed)
+	assert.Equal(t, 10.0, qs.Throughput)
+	assert.Equal(t, 5.0, qs.QueryWorkerStats[1].Throughput)
+	assert.Equal(t, 2.5, qs.QueryWorkerStats[2].QueryHostnameStats["host2"].Throughput)
+	assert.Equal(t, 7.5, qs.QueryHostnameStats["host1"].Throughput)
+
+	summary := qs.String()
+	assert.Contains(t, summary, "Start time: 2024-01-01T00:00:00Z\n"+
+		"End time: 2024-01-01T00:00:00.4Z\n"+
+		"Elapsed time: 400ms\n"+
+		"Throughput: 10.0 queries/s\n"+
+		"Total query time (sum of all queries): 1.2s\n")
+
+	data, err := json.Marshal(qs)
+	assert.NoError(t, err)
+	var decoded struct {
+		StartTime time.Time `json:"start_time"`
+		Elapsed   struct {
+			Nanoseconds int64 `json:"ns"`
+		} `json:"elapsed"`
+		Throughput float64 `json:"throughput"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, start, decoded.StartTime)
+	assert.Equal(t, int64(400*time.Millisecond), decoded.Elapsed.Nanoseconds)
+	assert.Equal(t, 10.0, decoded.Throughput)
+}
+
+func TestParseBreakdown(t *testing.T) {
+	breakdown, err := ParseBreakdown("worker, host")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{BreakdownWorker, BreakdownHostname}, breakdown)
+
+	_, err = ParseBreakdown("worker,database")
+	assert.Error(t, err)
+}
+
+func TestStats_StringWarmup(t *testing.T) {
+	warmup := Stats{}
+	warmup.CalculateStats([]QueryTaskResult{{Worker: 1, Hostname: "host1", Duration: time.Second, Warmup: true}}, nil)
+
+	qs := Stats{}
+	qs.CalculateStats([]QueryTaskResult{{Worker: 1, Hostname: "host1", Duration: time.Millisecond}}, nil)
+	assert.NotContains(t, qs.String(), "WARM-UP STATS")
+
+	qs.Partial = true
+	qs.Warmup = &warmup
+	assert.Contains(t, qs.String(), "PARTIAL STATS")
+	assert.Regexp(t, `(?s)STATS.*Maximum query time: 1ms.*WARM-UP STATS.*Maximum query time: 1s`, qs.String())
+}
+
+func TestCalculateStatsVerification(t *testing.T) {
+	params := &QueryParams{Hostname: "host1", StartTime: "2017-01-01 08:59:22", EndTime: "2017-01-01 09:59:22"}
+	expectedResults := NewExpectedResults()
+	expectedResults.Set(*params, ExpectedResult{Rows: 2, Checksum: "abc"})
+
+	checked, err := expectedResults.Verify(params, QueryResponse{Rows: 2, Checksum: "abc"})
+	assert.True(t, checked)
+	assert.NoError(t, err)
+	checked, err = expectedResults.Verify(&QueryParams{Hostname: "host2"}, QueryResponse{Rows: 2, Checksum: "abc"})
+	assert.False(t, checked)
+	assert.NoError(t, err)
+	checked, mismatch := expectedResults.Verify(params, QueryResponse{Rows: 1, Checksum: "def"})
+	assert.True(t, checked)
+	var verificationErr *VerificationError
+	if assert.ErrorAs(t, mismatch, &verificationErr) {
+		assert.Equal(t, ExpectedResult{Rows: 1, Checksum: "def"}, verificationErr.Actual)
+	}
+
+	qs := Stats{}
+	qs.CalculateStats(
+		[]QueryTaskResult{
+			{Worker: 1, Hostname: "host1", Duration: time.Millisecond, Rows: 2, Verified: true},
+			{Worker: 1, Hostname: "host2", Duration: time.Millisecond},
+		},
+		[]QueryTaskErr{
+			{QueryTaskResult: QueryTaskResult{Worker: 2, Hostname: "host1", Verified: true}, Err: mismatch},
+			{QueryTaskResult: QueryTaskResult{Worker: 2, Hostname: "host1"}, Err: errors.New("connection refused")},
+		},
+	)
+	assert.Equal(t, 1, qs.TotalVerified)
+	assert.Equal(t, 1, qs.TotalMismatches)
+	assert.Equal(t, 2, qs.TotalErrs)
+	assert.Contains(t, qs.String(), "Verified results: 1\nResult mismatches: 1\n")
+}
+
+func TestCalculateStatsTimeouts(t *testing.T) {
+	qs := Stats{}
+	qs.CalculateStats(nil, []QueryTaskErr{
+		{QueryTaskResult: QueryTaskResult{Worker: 1, Hostname: "host1"}, Err: &TimeoutError{Timeout: time.Second, Err: context.DeadlineExceeded}},
+		{QueryTaskResult: QueryTaskResult{Worker: 1, Hostname: "host1"}, Err: errors.New("connection refused")},
+	})
+	assert.Equal(t, 2, qs.TotalErrs)
+	assert.Equal(t, 1, qs.TotalTimeouts)
+	assert.Contains(t, qs.String(), "Total Errors: 2\nQuery timeouts: 1\n")
+}
+
+func TestCalculateStatsRetries(t *testing.T) {
+	qs := Stats{}
+	qs.CalculateStats(
+		[]QueryTaskResult{
+			{Worker: 1, Hostname: "host1", Duration: time.Millisecond, Attempts: 1},
+			{Worker: 1, Hostname: "host1", Duration: time.Millisecond, Attempts: 3},
+			{Worker: 2, Hostname: "host2", Duration: time.Millisecond},
+		},
+		[]QueryTaskErr{{QueryTaskResult: QueryTaskResult{Worker: 2, Hostname: "host2", Attempts: 3}, Err: errors.New("too many connections")}},
+	)
+	assert.Equal(t, RetryStats{FirstTrySuccess: 2, RetriedSuccess: 1, TotalRetries: 4}, qs.RetryStats)
+	assert.Contains(t, qs.String(), "Successful on first try: 2\nSuccessful after retries: 1\nTotal retries: 4\n")
+
+	qs = Stats{}
+	qs.CalculateStats([]QueryTaskResult{{Worker: 1, Hostname: "host1", Duration: time.Millisecond, Attempts: 1}}, nil)
+	assert.NotContains(t, qs.String(), "Total retries")
+}
+
+func TestCalculateRecordedStatsRejectedRows(t *testing.T) {
+	recorder := NewRecorder()
+	for line := 2; line <= 2+maxRejectedRows; line++ {
+		recorder.RecordParseErr(line, errors.New("invalid format: hostname cannot be empty"))
+	}
+
+	qs := NewStats(nil)
+	qs.CalculateRecordedStats(recorder)
+	assert.Equal(t, maxRejectedRows+1, qs.TotalRejectedRows)
+	assert.Equal(t, RejectedRow{Line: 2, Reason: "invalid format: hostname cannot be empty"}, qs.RejectedRows[0])
+	assert.Equal(t, 0, qs.TotalErrs)
+
+	summary := qs.String()
+	assert.Contains(t, summary, "Rejected input rows: 21\n  line 2: invalid format: hostname cannot be empty\n")
+	assert.Contains(t, summary, "  ... (1 more)\n")
+	assert.NotContains(t, summary, "line 22")
+
+	data, err := json.Marshal(qs)
+	if !assert.NoError(t, err) {
+		return
+	}
+	var report struct {
+		TotalRejectedRows int              `json:"total_rejected_rows"`
+		RejectedRows      []map[string]any `json:"rejected_rows"`
+	}
+	if assert.NoError(t, json.Unmarshal(data, &report)) {
+		assert.Equal(t, maxRejectedRows+1, report.TotalRejectedRows)
+		assert.Len(t, report.RejectedRows, maxRejectedRows+1)
+		assert.Equal(t, map[string]any{"line": float64(22), "reason": "invalid format: hostname cannot be empty"}, report.RejectedRows[maxRejectedRows])
+	}
+}
+
+func TestStats_StringUtilization(t *testing.T) {
+	qs := Stats{}
+	qs.CalculateStats([]QueryTaskResult{{Worker: 1, Hostname: "host1", Duration: time.Millisecond}}, nil)
+	assert.NotContains(t, qs.String(), "WORKER UTILIZATION")
+
+	qs.Utilization = []WorkerUtilization{
+		{Worker: 1, Tasks: 12, BusyTime: 900 * time.Millisecond, Utilization: 0.9},
+		{Worker: 2, Tasks: 8, Stolen: 3, BusyTime: 450 * time.Millisecond, Utilization: 0.45},
+	}
+	assert.Regexp(t, `(?s)WORKER UTILIZATION.*\n\s+1\s+12\s+0\s+900ms\s+90\.0%.*\n\s+2\s+8\s+3\s+450ms\s+45\.0%`, qs.String())
+}
+
+func TestCalculateRecordedStatsErrorSample(t *testing.T) {
+	recorder := NewRecorder()
+	start := time.Date(2017, 1, 1, 8, 0, 0, 0, time.UTC)
+	for i := 0; i < maxRecordedErrs+10; i++ {
+		recorder.RecordErr(QueryTaskErr{
+			QueryTaskResult: QueryTaskResult{Worker: 1, Hostname: "host1"},
+			RawQuery:        fmt.Sprintf("host1,%d", i),
+			Time:            start.Add(time.Duration(i) * time.Second),
+			Err:             &TimeoutError{Timeout: time.Second, Err: context.DeadlineExceeded},
+		})
+		recorder.RecordParseErr(i+2, errors.New("invalid format: hostname cannot be empty"))
+	}
+
+	qs := NewStats(nil)
+	qs.CalculateRecordedStats(recorder)
+	assert.Equal(t, maxRecordedErrs+10, qs.TotalErrs)
+	assert.Equal(t, maxRecordedErrs+10, qs.TotalTimeouts)
+	assert.Len(t, qs.QueryTaskErrs, maxRecordedErrs)
+	assert.Equal(t, "host1,0", qs.QueryTaskErrs[0].RawQuery)
+	assert.Equal(t, maxRecordedErrs+10, qs.TotalRejectedRows)
+	assert.Len(t, qs.RejectedRows, maxRecordedErrs)
+	if assert.Len(t, qs.ErrorClasses, 2) {
+		assert.Equal(t, maxRecordedErrs+10, qs.ErrorClasses[0].Count)
+		assert.Equal(t, maxRecordedErrs+10, qs.ErrorClasses[1].Count)
+	}
+	assert.Contains(t, qs.String(), fmt.Sprintf("  ... (%d more)\n", maxRecordedErrs+10-maxRejectedRows))
+}
+
+func TestRecorder_Close(t *testing.T) {
+	recorder := NewRecorder()
+	recorder.Record(QueryTaskResult{Worker: 1, Hostname: "host1", Duration: time.Millisecond})
+	recorder.Close()
+	recorder.Record(QueryTaskResult{Worker: 1, Hostname: "host1", Duration: time.Second})
+	recorder.RecordErr(QueryTaskErr{QueryTaskResult: QueryTaskResult{Worker: 1, Hostname: "host1"}, Err: context.Canceled})
+	recorder.RecordParseErr(2, errors.New("invalid format: hostname cannot be empty"))
+
+	qs := NewStats(nil)
+	qs.CalculateRecordedStats(recorder)
+	assert.Equal(t, 1, qs.TotalSuccess)
+	assert.Equal(t, time.Millisecond, qs.MaxQueryTime)
+	assert.Equal(t, 0, qs.TotalErrs)
+	assert.Equal(t, 0, qs.TotalRejectedRows)
+}
@@ -0,0 +1,187 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// DefaultSweep are the worker counts of a sweep when none are configured.
+var DefaultSweep = []int{1, 2, 4, 8, 16, 32, 64}
+
+// KneePercentile is the query time percentile compared with the throughput to
+// find the knee of a sweep.
+const KneePercentile = 99
+
+// ParseSweep parses a comma separated list of worker counts such as "1,2,4,8".
+// The result is sorted and without duplicates.
+func ParseSweep(value string) ([]int, error) {
+	var workers []int
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		count, err := strconv.Atoi(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid worker count: %s is not a number", field)
+		}
+		if count < 1 {
+			return nil, fmt.Errorf("invalid worker count: %s is not >= 1", field)
+		}
+		workers = append(workers, count)
+	}
+	if len(workers) == 0 {
+		return nil, fmt.Errorf("invalid sweep: no worker counts")
+	}
+
+	sort.Ints(workers)
+	unique := workers[:0]
+	for i, count := range workers {
+		if i == 0 || count != workers[i-1] {
+			unique = append(unique, count)
+		}
+	}
+	return unique, nil
+}
+
+// SweepPercentiles returns the percentiles with the KneePercentile added, as
+// the sweep needs it to find the knee.
+func SweepPercentiles(percentiles []float64) []float64 {
+	for _, percentile := range percentiles {
+		if percentile == KneePercentile {
+			return percentiles
+		}
+	}
+	withKnee := append([]float64{KneePercentile}, percentiles...)
+	sort.Float64s(withKnee)
+	return withKnee
+}
+
+// SweepStep holds the stats of the workload run with a number of workers.
+type SweepStep struct {
+	Workers int
+	Stats   Stats
+}
+
+// Throughput returns the successful queries per second of the step.
+func (step SweepStep) Throughput() float64 {
+	return step.Stats.Throughput
+}
+
+// kneeQueryTime returns the KneePercentile query time of the step.
+func (step SweepStep) kneeQueryTime() time.Duration {
+	for _, percentile := range step.Stats.Percentiles {
+		if percentile.Percentile == KneePercentile {
+			return percentile.QueryTime
+		}
+	}
+	return step.Stats.MaxQueryTime
+}
+
+// Sweep holds the steps of a concurrency sweep, by increasing number of workers.
+type Sweep struct {
+	Steps []SweepStep
+}
+
+// Knee returns the index of the first step where the p99 query time grew by a
+// larger factor than the throughput since the previous step, or -1 when the
+// throughput kept up on every step.
+func (s Sweep) Knee() int {
+	for i := 1; i < len(s.Steps); i++ {
+		previous, step := s.Steps[i-1], s.Steps[i]
+		if previous.Throughput() == 0 || previous.kneeQueryTime() == 0 {
+			continue
+		}
+		throughputGrowth := step.Throughput() / previous.Throughput()
+		latencyGrowth := float64(step.kneeQueryTime()) / float64(previous.kneeQueryTime())
+		if latencyGrowth > throughputGrowth {
+			return i
+		}
+	}
+	return -1
+}
+
+// String renders a table with the throughput and the query times of every step,
+// followed by the knee.
+func (s Sweep) String() string {
+	var table strings.Builder
+	table.WriteString("\nCONCURRENCY SWEEP\n\n")
+
+	var percentiles []Percentile
+	if len(s.Steps) > 0 {
+		percentiles = s.Steps[0].Stats.Percentiles
+	}
+	writer := tabwriter.NewWriter(&table, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprint(writer, "Workers\tQueries\tErrors\tElapsed\tQueries/s\tMin\tMedian\tAvg\tMax\t")
+	for _, percentile := range percentiles {
+		fmt.Fprintf(writer, "%s\t", percentile.Label())
+	}
+	fmt.Fprintln(writer)
+	for _, step := range s.Steps {
+		fmt.Fprintf(writer, "%d\t%d\t%d\t%v\t%.1f\t%v\t%v\t%v\t%v\t",
+			step.Workers,
+			step.Stats.TotalSuccess,
+			step.Stats.TotalErrs,
+			step.Stats.Elapsed.Round(time.Millisecond),
+			step.Throughput(),
+			step.Stats.MinQueryTime,
+			step.Stats.MedianQueryTime,
+			step.Stats.AvgQueryTime,
+			step.Stats.MaxQueryTime,
+		)
+		for _, percentile := range step.Stats.Percentiles {
+			fmt.Fprintf(writer, "%v\t", percentile.QueryTime)
+		}
+		fmt.Fprintln(writer)
+	}
+	writer.Flush()
+
+	knee := s.Knee()
+	if knee < 0 {
+		if len(s.Steps) > 0 {
+			fmt.Fprintf(&table, "\nKnee: not found, the throughput kept up with the p%d query time up to %d workers\n", KneePercentile, s.Steps[len(s.Steps)-1].Workers)
+		}
+		return table.String()
+	}
+	previous, step := s.Steps[knee-1], s.Steps[knee]
+	fmt.Fprintf(&table, "\nKnee: %d workers, the p%d query time grew x%.2f and the throughput x%.2f from %d workers\n",
+		step.Workers,
+		KneePercentile,
+		float64(step.kneeQueryTime())/float64(previous.kneeQueryTime()),
+		step.Throughput()/previous.Throughput(),
+		previous.Workers,
+	)
+	return table.String()
+}
+
+type sweepStepJSON struct {
+	Workers    int     `json:"workers"`
+	Throughput float64 `json:"throughput"`
+	Stats      Stats   `json:"stats"`
+}
+
+type sweepJSON struct {
+	Steps []sweepStepJSON `json:"steps"`
+	// KneeWorkers is the worker count of the knee step, null when not found.
+	KneeWorkers *int `json:"knee_workers"`
+}
+
+func (s Sweep) MarshalJSON() ([]byte, error) {
+	sweep := sweepJSON{Steps: make([]sweepStepJSON, 0, len(s.Steps))}
+	for _, step := range s.Steps {
+		sweep.Steps = append(sweep.Steps, sweepStepJSON{
+			Workers:    step.Workers,
+			Throughput: step.Throughput(),
+			Stats:      step.Stats,
+		})
+	}
+	if knee := s.Knee(); knee >= 0 {
+		sweep.KneeWorkers = &s.Steps[knee].Workers
+	}
+	return json.Marshal(sweep)
+}
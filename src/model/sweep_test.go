@@ -0,0 +1,68 @@
+package model
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSweep(t *testing.T) {
+	sweep, err := ParseSweep("8, 1,2,4,2")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 4, 8}, sweep)
+
+	_, err = ParseSweep("1,two")
+	assert.EqualError(t, err, "invalid worker count: two is not a number")
+	_, err = ParseSweep("0,1")
+	assert.EqualError(t, err, "invalid worker count: 0 is not >= 1")
+	_, err = ParseSweep(" , ")
+	assert.EqualError(t, err, "invalid sweep: no worker counts")
+}
+
+func TestSweepPercentiles(t *testing.T) {
+	assert.Equal(t, []float64{50, 99}, SweepPercentiles([]float64{50}))
+	assert.Equal(t, []float64{50, 99, 99.9}, SweepPercentiles([]float64{50, 99, 99.9}))
+}
+
+// sweepStep returns a step that ran the queries in a second with the p99 query time.
+func sweepStep(workers int, queries int, p99 time.Duration) SweepStep {
+	step := SweepStep{Workers: workers}
+	step.Stats.TotalSuccess = queries
+	step.Stats.SetRunTime(time.Time{}, time.Time{}.Add(time.Second))
+	step.Stats.Percentiles = []Percentile{{Percentile: 99, QueryTime: p99}}
+	return step
+}
+
+func TestSweepKnee(t *testing.T) {
+	sweep := Sweep{Steps: []SweepStep{
+		sweepStep(1, 100, 10*time.Millisecond),
+		sweepStep(2, 200, 10*time.Millisecond),
+		sweepStep(4, 380, 11*time.Millisecond),
+		sweepStep(8, 420, 20*time.Millisecond),
+		sweepStep(16, 430, 40*time.Millisecond),
+	}}
+	assert.Equal(t, 3, sweep.Knee())
+	assert.Contains(t, sweep.String(), "Knee: 8 workers, the p99 query time grew x1.82 and the throughput x1.11 from 4 workers\n")
+
+	data, err := json.Marshal(sweep)
+	assert.NoError(t, err)
+	var decoded struct {
+		Steps []struct {
+			Workers    int     `json:"workers"`
+			Throughput float64 `json:"throughput"`
+		} `json:"steps"`
+		KneeWorkers *int `json:"knee_workers"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &decoded))
+	assert.Len(t, decoded.Steps, 5)
+	assert.Equal(t, 380.0, decoded.Steps[2].Throughput)
+	if assert.NotNil(t, decoded.KneeWorkers) {
+		assert.Equal(t, 8, *decoded.KneeWorkers)
+	}
+
+	linear := Sweep{Steps: sweep.Steps[:3]}
+	assert.Equal(t, -1, linear.Knee())
+	assert.Contains(t, linear.String(), "Knee: not found, the throughput kept up with the p99 query time up to 4 workers\n")
+}
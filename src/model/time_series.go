@@ -0,0 +1,308 @@
+package model
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// IntervalStats holds the stats of the queries that completed within an
+// interval of the run, starting at Start. The throughput is over the part of
+// the interval within the run, as the first and the last ones may be partial.
+type IntervalStats struct {
+	Start           time.Time
+	Count           int
+	Errs            int
+	Throughput      float64
+	MinQueryTime    time.Duration
+	MedianQueryTime time.Duration
+	AvgQueryTime    time.Duration
+	MaxQueryTime    time.Duration
+	Percentiles     []Percentile
+}
+
+// TimeSeries holds the stats of every interval of the run, in time order. The
+// intervals without any completed query are included, so the series has no gaps.
+type TimeSeries struct {
+	Interval    time.Duration
+	Intervals   []IntervalStats
+	percentiles []float64
+	// origin is the time the intervals are aligned on.
+	origin time.Time
+}
+
+// intervalRecord aggregates the results of one interval.
+type intervalRecord struct {
+	*Histogram
+	errs int
+}
+
+// timeSeriesRecord buckets the results by their completion time into intervals
+// aligned on the start of the run.
+type timeSeriesRecord struct {
+	start     time.Time
+	interval  time.Duration
+	intervals map[time.Time]*intervalRecord
+}
+
+func newTimeSeriesRecord(start time.Time, interval time.Duration) *timeSeriesRecord {
+	return &timeSeriesRecord{
+		// The monotonic clock reading is dropped to use the starts as keys.
+		start:     start.Round(0),
+		interval:  interval,
+		intervals: make(map[time.Time]*intervalRecord),
+	}
+}
+
+func (t *timeSeriesRecord) at(end time.Time) *intervalRecord {
+	index := end.Sub(t.start) / t.interval
+	if end.Before(t.start) && end.Sub(t.start)%t.interval != 0 {
+		index--
+	}
+	start := t.start.Add(index * t.interval)
+	record, exists := t.intervals[start]
+	if !exists {
+		record = &intervalRecord{Histogram: NewHistogram()}
+		t.intervals[start] = record
+	}
+	return record
+}
+
+func (t *timeSeriesRecord) record(end time.Time, queryTime time.Duration) {
+	if end.IsZero() {
+		return
+	}
+	t.at(end).Record(queryTime)
+}
+
+func (t *timeSeriesRecord) recordErr(end time.Time) {
+	if end.IsZero() {
+		return
+	}
+	t.at(end).errs++
+}
+
+// calculate returns the stats of every interval from the first to the last one.
+func (t *timeSeriesRecord) calculate(percentiles []float64) *TimeSeries {
+	series := &TimeSeries{Interval: t.interval, percentiles: percentiles, origin: t.start}
+	if len(t.intervals) == 0 {
+		return series
+	}
+	starts := make([]time.Time, 0, len(t.intervals))
+	for start := range t.intervals {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+
+	for start := starts[0]; !start.After(starts[len(starts)-1]); start = start.Add(t.interval) {
+		stats := IntervalStats{Start: start}
+		record, exists := t.intervals[start]
+		if exists {
+			stats.Errs = record.errs
+		}
+		if exists && record.Count() > 0 {
+			values := record.valuesAtPercentiles(percentiles)
+			stats.Count = record.Count()
+			stats.Throughput = float64(record.Count()) / t.interval.Seconds()
+			stats.MinQueryTime = record.Min()
+			stats.MedianQueryTime = record.Median()
+			stats.AvgQueryTime = record.Mean()
+			stats.MaxQueryTime = record.Max()
+			for i, percentile := range percentiles {
+				stats.Percentiles = append(stats.Percentiles, Percentile{Percentile: percentile, QueryTime: values[i]})
+			}
+		}
+		series.Intervals = append(series.Intervals, stats)
+	}
+	return series
+}
+
+// setRunTime spans the series over the run from start to end, and calculates
+// the throughput of every interval over its part within the run.
+func (s *TimeSeries) setRunTime(start time.Time, end time.Time) {
+	if !end.After(start) {
+		return
+	}
+
+	// The measured run may start after the origin, such as after a warm-up.
+	first := s.origin
+	if start.After(first) {
+		first = first.Add(start.Sub(first) / s.Interval * s.Interval)
+	}
+	if len(s.Intervals) > 0 && s.Intervals[0].Start.Before(first) {
+		first = s.Intervals[0].Start
+	}
+	recorded := make(map[time.Time]IntervalStats, len(s.Intervals))
+	for _, interval := range s.Intervals {
+		recorded[interval.Start] = interval
+	}
+
+	last := first
+	if len(s.Intervals) > 0 {
+		last = s.Intervals[len(s.Intervals)-1].Start
+	}
+	intervals := make([]IntervalStats, 0, len(s.Intervals))
+	for intervalStart := first; intervalStart.Before(end) || !intervalStart.After(last); intervalStart = intervalStart.Add(s.Interval) {
+		interval, exists := recorded[intervalStart]
+		if !exists {
+			interval = IntervalStats{Start: intervalStart}
+		}
+		interval.Throughput = 0
+		intervalEnd := intervalStart.Add(s.Interval)
+		covered := minTime(intervalEnd, end).Sub(maxTime(intervalStart, start))
+		if covered > 0 {
+			interval.Throughput = float64(interval.Count) / covered.Seconds()
+		}
+		intervals = append(intervals, interval)
+	}
+	s.Intervals = intervals
+}
+
+func minTime(a time.Time, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a time.Time, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// String renders a table with the stats of every interval.
+func (s TimeSeries) String() string {
+	var table strings.Builder
+	table.WriteString("\nTIME SERIES\n\n")
+
+	writer := tabwriter.NewWriter(&table, 0, 0, 2, ' ', tabwriter.AlignRight)
+	labels := s.percentileLabels()
+	fmt.Fprint(writer, "Start\tCount\tErrors\tQueries/s\tMin\tMedian\tAvg\tMax\t")
+	for _, label := range labels {
+		fmt.Fprintf(writer, "%s\t", label)
+	}
+	fmt.Fprintln(writer)
+	for _, interval := range s.Intervals {
+		fmt.Fprintf(writer, "%s\t%d\t%d\t%.1f\t%v\t%v\t%v\t%v\t",
+			interval.Start.Format("15:04:05.000"),
+			interval.Count,
+			interval.Errs,
+			interval.Throughput,
+			interval.MinQueryTime,
+			interval.MedianQueryTime,
+			interval.AvgQueryTime,
+			interval.MaxQueryTime,
+		)
+		for _, percentile := range interval.Percentiles {
+			fmt.Fprintf(writer, "%v\t", percentile.QueryTime)
+		}
+		// Keep the columns aligned for the intervals without queries.
+		for range labels[len(interval.Percentiles):] {
+			fmt.Fprint(writer, "\t")
+		}
+		fmt.Fprintln(writer)
+	}
+	writer.Flush()
+
+	return table.String()
+}
+
+// WriteCSV writes the stats of every interval as CSV, with the query times in
+// nanoseconds. The query time columns are empty for the intervals without
+// successful queries.
+func (s TimeSeries) WriteCSV(output io.Writer) error {
+	writer := csv.NewWriter(output)
+	header := []string{"start", "count", "errors", "throughput", "min_ns", "median_ns", "avg_ns", "max_ns"}
+	labels := s.percentileLabels()
+	for _, label := range labels {
+		header = append(header, label+"_ns")
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, interval := range s.Intervals {
+		record := []string{
+			interval.Start.Format(time.RFC3339Nano),
+			strconv.Itoa(interval.Count),
+			strconv.Itoa(interval.Errs),
+			strconv.FormatFloat(interval.Throughput, 'f', -1, 64),
+		}
+		if interval.Count == 0 {
+			record = append(record, make([]string, len(header)-len(record))...)
+		} else {
+			queryTimes := []time.Duration{interval.MinQueryTime, interval.MedianQueryTime, interval.AvgQueryTime, interval.MaxQueryTime}
+			for _, percentile := range interval.Percentiles {
+				queryTimes = append(queryTimes, percentile.QueryTime)
+			}
+			for _, queryTime := range queryTimes {
+				record = append(record, strconv.FormatInt(int64(queryTime), 10))
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+type timeSeriesJSON struct {
+	Interval  durationJSON        `json:"interval"`
+	Intervals []intervalStatsJSON `json:"intervals"`
+}
+
+type intervalStatsJSON struct {
+	Start           time.Time        `json:"start"`
+	Count           int              `json:"count"`
+	Errs            int              `json:"errors"`
+	Throughput      float64          `json:"throughput"`
+	MinQueryTime    durationJSON     `json:"min_query_time"`
+	MedianQueryTime durationJSON     `json:"median_query_time"`
+	AvgQueryTime    durationJSON     `json:"avg_query_time"`
+	MaxQueryTime    durationJSON     `json:"max_query_time"`
+	Percentiles     []percentileJSON `json:"percentiles,omitempty"`
+}
+
+func (s TimeSeries) MarshalJSON() ([]byte, error) {
+	series := timeSeriesJSON{
+		Interval:  newDurationJSON(s.Interval),
+		Intervals: make([]intervalStatsJSON, 0, len(s.Intervals)),
+	}
+	for _, interval := range s.Intervals {
+		intervalJSON := intervalStatsJSON{
+			Start:           interval.Start,
+			Count:           interval.Count,
+			Errs:            interval.Errs,
+			Throughput:      interval.Throughput,
+			MinQueryTime:    newDurationJSON(interval.MinQueryTime),
+			MedianQueryTime: newDurationJSON(interval.MedianQueryTime),
+			AvgQueryTime:    newDurationJSON(interval.AvgQueryTime),
+			MaxQueryTime:    newDurationJSON(interval.MaxQueryTime),
+		}
+		for _, percentile := range interval.Percentiles {
+			intervalJSON.Percentiles = append(intervalJSON.Percentiles, percentileJSON{
+				Percentile: percentile.Percentile,
+				QueryTime:  newDurationJSON(percentile.QueryTime),
+			})
+		}
+		series.Intervals = append(series.Intervals, intervalJSON)
+	}
+	return json.Marshal(series)
+}
+
+func (s TimeSeries) percentileLabels() []string {
+	labels := make([]string, len(s.percentiles))
+	for i, percentile := range s.percentiles {
+		labels[i] = Percentile{Percentile: percentile}.Label()
+	}
+	return labels
+}
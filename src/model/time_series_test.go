@@ -0,0 +1,86 @@
+package model
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCalculateRecordedStatsTimeSeries(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	recorder := NewRecorderWithInterval(start, time.Second)
+	recorder.Record(QueryTaskResult{Worker: 1, Hostname: "host1", Duration: 2 * time.Millisecond, End: start.Add(100 * time.Millisecond)})
+	recorder.Record(QueryTaskResult{Worker: 1, Hostname: "host1", Duration: 4 * time.Millisecond, End: start.Add(900 * time.Millisecond)})
+	recorder.RecordErr(QueryTaskErr{QueryTaskResult: QueryTaskResult{Worker: 2, Hostname: "host2"}, Time: start.Add(1500 * time.Millisecond), Err: errors.New("boom")})
+	recorder.Record(QueryTaskResult{Worker: 2, Hostname: "host2", Duration: 8 * time.Millisecond, End: start.Add(3200 * time.Millisecond)})
+
+	qs := NewStats([]float64{99})
+	qs.CalculateRecordedStats(recorder)
+	if !assert.NotNil(t, qs.TimeSeries) || !assert.Len(t, qs.TimeSeries.Intervals, 4) {
+		return
+	}
+	assert.Equal(t, IntervalStats{
+		Start:           start,
+		Count:           2,
+		Throughput:      2,
+		MinQueryTime:    2 * time.Millisecond,
+		MedianQueryTime: qs.TimeSeries.Intervals[0].MedianQueryTime,
+		AvgQueryTime:    3 * time.Millisecond,
+		MaxQueryTime:    4 * time.Millisecond,
+		Percentiles:     []Percentile{{Percentile: 99, QueryTime: 4 * time.Millisecond}},
+	}, qs.TimeSeries.Intervals[0])
+	// The median is the mean of the two middle query times, within the histogram precision.
+	assertQueryTime(t, 3*time.Millisecond, qs.TimeSeries.Intervals[0].MedianQueryTime)
+	assert.Equal(t, IntervalStats{Start: start.Add(time.Second), Errs: 1}, qs.TimeSeries.Intervals[1])
+	assert.Equal(t, IntervalStats{Start: start.Add(2 * time.Second)}, qs.TimeSeries.Intervals[2])
+	assert.Equal(t, 1, qs.TimeSeries.Intervals[3].Count)
+	assert.Contains(t, qs.String(), "TIME SERIES")
+
+	var output strings.Builder
+	assert.NoError(t, qs.TimeSeries.WriteCSV(&output))
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Len(t, lines, 5)
+	assert.Equal(t, "start,count,errors,throughput,min_ns,median_ns,avg_ns,max_ns,p99_ns", lines[0])
+	assert.Equal(t, "2024-01-01T00:00:01Z,0,1,0,,,,,", lines[2])
+
+	qs = NewStats([]float64{99})
+	qs.CalculateRecordedStats(NewRecorderWithInterval(start, time.Second))
+	assert.Empty(t, qs.TimeSeries.Intervals)
+	assert.NotContains(t, qs.String(), "TIME SERIES")
+	output.Reset()
+	assert.NoError(t, qs.TimeSeries.WriteCSV(&output))
+	assert.Equal(t, "start,count,errors,throughput,min_ns,median_ns,avg_ns,max_ns,p99_ns\n", output.String())
+
+	qs = NewStats(nil)
+	qs.CalculateRecordedStats(NewRecorder())
+	assert.Nil(t, qs.TimeSeries)
+}
+
+func TestStats_SetRunTimeTimeSeries(t *testing.T) {
+	// The intervals are aligned on the start of the recorder, not on the clock.
+	start := time.Date(2024, 1, 1, 0, 0, 0, 250_000_000, time.UTC)
+	recorder := NewRecorderWithInterval(start, time.Second)
+	recorder.Record(QueryTaskResult{Worker: 1, Hostname: "host1", Duration: time.Millisecond, End: start.Add(600 * time.Millisecond)})
+	recorder.Record(QueryTaskResult{Worker: 1, Hostname: "host1", Duration: time.Millisecond, End: start.Add(1200 * time.Millisecond)})
+	recorder.Record(QueryTaskResult{Worker: 1, Hostname: "host1", Duration: time.Millisecond, End: start.Add(1800 * time.Millisecond)})
+
+	qs := NewStats(nil)
+	qs.CalculateRecordedStats(recorder)
+	// The measured run starts after a warm-up and ends within an interval.
+	qs.SetRunTime(start.Add(500*time.Millisecond), start.Add(3250*time.Millisecond))
+	if !assert.Len(t, qs.TimeSeries.Intervals, 4) {
+		return
+	}
+	starts := make([]time.Time, 0, 4)
+	throughputs := make([]float64, 0, 4)
+	for _, interval := range qs.TimeSeries.Intervals {
+		starts = append(starts, interval.Start)
+		throughputs = append(throughputs, interval.Throughput)
+	}
+	assert.Equal(t, []time.Time{start, start.Add(time.Second), start.Add(2 * time.Second), start.Add(3 * time.Second)}, starts)
+	// The first and the last intervals only count the part of them within the run.
+	assert.Equal(t, []float64{2, 2, 0, 0}, throughputs)
+}
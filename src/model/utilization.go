@@ -0,0 +1,40 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// WorkerUtilization tells how busy a worker of the pool was: the tasks it ran,
+// including the ones it took from the queue of another worker, and the share of
+// the run it spent running them.
+type WorkerUtilization struct {
+	Worker      Worker
+	Tasks       int
+	Stolen      int
+	BusyTime    time.Duration
+	Utilization float64
+}
+
+// utilizationTable renders the utilization of every worker, sorted by worker.
+func utilizationTable(utilization []WorkerUtilization) string {
+	var table strings.Builder
+	table.WriteString("\nWORKER UTILIZATION\n\n")
+
+	writer := tabwriter.NewWriter(&table, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(writer, "Worker\tTasks\tStolen\tBusy\tUtilization\t")
+	for _, worker := range utilization {
+		fmt.Fprintf(writer, "%d\t%d\t%d\t%v\t%.1f%%\t\n",
+			worker.Worker,
+			worker.Tasks,
+			worker.Stolen,
+			worker.BusyTime.Round(time.Microsecond),
+			worker.Utilization*100,
+		)
+	}
+	writer.Flush()
+
+	return table.String()
+}
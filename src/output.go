@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/molinama/timescale/src/model"
+)
+
+const (
+	OUTPUT_TEXT = "text" // Human readable summary.
+	OUTPUT_JSON = "json" // Machine readable report.
+	OUTPUT_CSV  = "csv"  // Time series for spreadsheets and plotting tools.
+)
+
+// writeStats writes the stats in the configured format to the output file, or
+// to standard output when no file is given.
+func writeStats(config Config, stats model.Stats) error {
+	return writeOutput(config, func(output io.Writer) error {
+		return encodeStats(output, config, stats)
+	})
+}
+
+// writeSweep writes the steps of a sweep like writeStats.
+func writeSweep(config Config, sweep model.Sweep) error {
+	return writeOutput(config, func(output io.Writer) error {
+		return encodeSweep(output, config, sweep)
+	})
+}
+
+// writeTimeSeries writes the stats of every interval in the configured format to
+// the interval file.
+func writeTimeSeries(config Config, series *model.TimeSeries) error {
+	file, err := os.Create(config.seriesFile)
+	if err != nil {
+		return fmt.Errorf("cannot create interval file: %w", err)
+	}
+	if err := encodeTimeSeries(file, config, series); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func writeOutput(config Config, encode func(output io.Writer) error) error {
+	if config.outputFile == "" {
+		return encode(os.Stdout)
+	}
+
+	file, err := os.Create(config.outputFile)
+	if err != nil {
+		return fmt.Errorf("cannot create output file: %w", err)
+	}
+	if err := encode(file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+func encodeStats(output io.Writer, config Config, stats model.Stats) error {
+	switch config.outputFormat {
+	case OUTPUT_JSON:
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(stats)
+	default:
+		if _, err := fmt.Fprint(output, stats); err != nil {
+			return err
+		}
+		for _, dimension := range config.breakdown {
+			if _, err := fmt.Fprint(output, stats.Breakdown(dimension)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func encodeSweep(output io.Writer, config Config, sweep model.Sweep) error {
+	switch config.outputFormat {
+	case OUTPUT_JSON:
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(sweep)
+	default:
+		_, err := fmt.Fprint(output, sweep)
+		return err
+	}
+}
+
+func encodeTimeSeries(output io.Writer, config Config, series *model.TimeSeries) error {
+	switch config.seriesFormat {
+	case OUTPUT_JSON:
+		encoder := json.NewEncoder(output)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(series)
+	default:
+		return series.WriteCSV(output)
+	}
+}
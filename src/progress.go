@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	inputparser "github.com/molinama/timescale/src/input_parser"
+	"github.com/molinama/timescale/src/logging"
+	"github.com/molinama/timescale/src/model"
+)
+
+// startProgress writes a progress line to standard error at every progress
+// interval of the config until stop is called. The Progress is nil when the
+// progress is not reported. The total number of queries is only known when a
+// file is read once: its rows are counted in the background, so the run does
+// not wait for it.
+func startProgress(config Config) (progress *model.Progress, stop func()) {
+	if config.progress <= 0 {
+		return nil, func() {}
+	}
+
+	progress = model.NewProgress(0, config.duration)
+	if config.duration == 0 && config.csvFilePath != STDIN {
+		go func() {
+			rows, err := inputparser.CountRows(config.csvFilePath)
+			if err != nil {
+				logging.SugaredLog.Warnf("Cannot count the input rows: %v", err)
+				return
+			}
+			progress.SetTotal(rows)
+		}()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		reportProgress(ctx, progress, config.progress, os.Stderr)
+	}()
+	return progress, func() {
+		cancel()
+		<-done
+	}
+}
+
+func reportProgress(ctx context.Context, progress *model.Progress, interval time.Duration, output io.Writer) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			fmt.Fprintln(output, progress.Report(now))
+		}
+	}
+}
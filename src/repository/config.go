@@ -1,8 +1,9 @@
 package repository
 
 import (
+	"errors"
 	"fmt"
-	"log"
+	"io/fs"
 	"os"
 
 	"github.com/joho/godotenv"
@@ -17,14 +18,28 @@ const (
 	efault = "info"
 )
 
+const envFile = "./timescaledb/.env"
+
 type config struct {
 	dbConnString string
 }
 
-func loadConfig() (*config, error) {
-	err := godotenv.Load("./timescaledb/.env")
+// LoadDSN returns the connection string built from the environment, as used
+// when no connection string is given.
+func LoadDSN() (string, error) {
+	dbConfig, err := loadConfig()
 	if err != nil {
-		log.Fatal("Error loading .env file")
+		return "", err
+	}
+	return dbConfig.dbConnString, nil
+}
+
+// loadConfig builds the connection string from the environment. The
+// ./timescaledb/.env file is loaded first when it exists.
+func loadConfig() (*config, error) {
+	err := godotenv.Load(envFile)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("cannot load %s: %w", envFile, err)
 	}
 
 	user := os.Getenv("TIMESCALE_USER")
@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ExecMode is how queries are sent to the database.
+type ExecMode string
+
+const (
+	// ExecModeSimple sends the query text with the arguments in a single round trip,
+	// so the database parses and plans it on every call.
+	ExecModeSimple ExecMode = "simple"
+	// ExecModePrepared prepares the query template once per connection and then
+	// only binds the arguments and executes it. It is the default of pgx.
+	ExecModePrepared ExecMode = "prepared"
+	// ExecModeExtended sends the query template and the arguments with the extended
+	// protocol in an unnamed statement, so it is parsed and planned on every call.
+	ExecModeExtended ExecMode = "extended"
+)
+
+func ParseExecMode(value string) (ExecMode, error) {
+	switch mode := ExecMode(value); mode {
+	case ExecModeSimple, ExecModePrepared, ExecModeExtended:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid mode: %s (expected %s, %s or %s)", value, ExecModeSimple, ExecModePrepared, ExecModeExtended)
+	}
+}
+
+func (mode ExecMode) queryExecMode() pgx.QueryExecMode {
+	switch mode {
+	case ExecModeSimple:
+		return pgx.QueryExecModeSimpleProtocol
+	case ExecModeExtended:
+		return pgx.QueryExecModeExec
+	default:
+		return pgx.QueryExecModeCacheStatement
+	}
+}
@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/molinama/timescale/src/model"
+)
+
+// querier runs a query on a pool or on a single connection.
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// PgxPoolRepository runs the queries on a native pgx connection pool, without
+// the database/sql layer. The prepared mode relies on the statement cache of
+// every connection, so the first query on a connection also prepares it.
+type PgxPoolRepository struct {
+	pool      *pgxpool.Pool
+	dedicated bool
+	conns     map[model.Worker]*pgxpool.Conn
+	connsMux  sync.Mutex
+}
+
+// NewPgxPoolRepository opens a pgx connection pool to the PostgreSQL compatible
+// database at dsn.
+func NewPgxPoolRepository(ctx context.Context, dsn string, mode ExecMode, poolConfig PoolConfig) (*PgxPoolRepository, error) {
+	config, err := newPgxPoolConfig(dsn, mode, poolConfig)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create repository: %w", err)
+	}
+	pool, err := pgxpool.NewWithConfig(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create repository: %w", err)
+	}
+
+	return &PgxPoolRepository{
+		pool:      pool,
+		dedicated: poolConfig.Dedicated,
+		conns:     make(map[model.Worker]*pgxpool.Conn),
+	}, nil
+}
+
+func newPgxPoolConfig(dsn string, mode ExecMode, poolConfig PoolConfig) (*pgxpool.Config, error) {
+	config, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
+	config.ConnConfig.DefaultQueryExecMode = mode.queryExecMode()
+
+	config.MaxConns = defaultMaxConns
+	if poolConfig.MaxConns > 0 {
+		config.MaxConns = int32(poolConfig.MaxConns)
+	}
+	if poolConfig.MinConns > 0 {
+		config.MinConns = int32(min(poolConfig.MinConns, int(config.MaxConns)))
+	}
+	if poolConfig.MaxConnLifetime > 0 {
+		config.MaxConnLifetime = poolConfig.MaxConnLifetime
+	}
+	if poolConfig.HealthCheckPeriod > 0 {
+		config.HealthCheckPeriod = poolConfig.HealthCheckPeriod
+	}
+	return config, nil
+}
+
+// RawQuery runs the query and reads all of its rows. Unless the worker has a
+// dedicated connection, the query time includes the wait for a free connection
+// of the pool.
+func (repository *PgxPoolRepository) RawQuery(ctx context.Context, params *model.QueryParams) (model.QueryResponse, error) {
+	var query querier = repository.pool
+	worker, ok := workerFromContext(ctx)
+	if repository.dedicated && ok {
+		conn, err := repository.conn(ctx, worker)
+		if err != nil {
+			return model.QueryResponse{}, err
+		}
+		defer repository.checkConn(worker, conn)
+		query = conn
+	}
+
+	start := time.Now()
+	rows, err := query.Query(ctx, params.QueryTemplate(), params.Args()...)
+	if err != nil {
+		return model.QueryResponse{Duration: time.Since(start)}, err
+	}
+	return readPgxRows(rows, rows.Conn().TypeMap(), start)
+}
+
+// Close releases the dedicated connections and closes the pool.
+func (repository *PgxPoolRepository) Close() error {
+	repository.connsMux.Lock()
+	for worker, conn := range repository.conns {
+		conn.Release()
+		delete(repository.conns, worker)
+	}
+	repository.connsMux.Unlock()
+
+	repository.pool.Close()
+	return nil
+}
+
+// conn returns the dedicated connection of the worker, acquiring it on the
+// first query of the worker. A worker runs a single query at a time, so only
+// the worker itself acquires its connection.
+func (repository *PgxPoolRepository) conn(ctx context.Context, worker model.Worker) (*pgxpool.Conn, error) {
+	repository.connsMux.Lock()
+	conn, exists := repository.conns[worker]
+	repository.connsMux.Unlock()
+	if exists {
+		return conn, nil
+	}
+
+	conn, err := repository.pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cannot acquire connection: %w", err)
+	}
+	repository.connsMux.Lock()
+	repository.conns[worker] = conn
+	repository.connsMux.Unlock()
+	return conn, nil
+}
+
+// checkConn gives a broken dedicated connection back to the pool, so the next
+// query of the worker acquires a new one.
+func (repository *PgxPoolRepository) checkConn(worker model.Worker, conn *pgxpool.Conn) {
+	if !conn.Conn().IsClosed() {
+		return
+	}
+
+	repository.connsMux.Lock()
+	defer repository.connsMux.Unlock()
+
+	if repository.conns[worker] == conn {
+		delete(repository.conns, worker)
+		conn.Release()
+	}
+}
+
+// readPgxRows reads all the rows of a query result, measuring the time to the
+// first and to the last row since start. The values are decoded with the type
+// map as the database/sql driver decodes them.
+func readPgxRows(rows pgx.Rows, typeMap *pgtype.Map, start time.Time) (model.QueryResponse, error) {
+	defer rows.Close()
+
+	digest := newResultDigest(start)
+	var row []any
+	for rows.Next() {
+		digest.firstRow()
+		fields := rows.FieldDescriptions()
+		row = row[:0]
+		for i, src := range rows.RawValues() {
+			value, err := driverValue(typeMap, fields[i], src)
+			if err != nil {
+				return digest.finish(), fmt.Errorf("cannot decode field %d: %w", i, err)
+			}
+			row = append(row, value)
+		}
+		digest.addRow(row)
+	}
+	return digest.finish(), rows.Err()
+}
+
+// driverValue decodes a value in the text or binary format of the field into
+// the value the pgx database/sql driver returns for it, nil for NULL.
+func driverValue(typeMap *pgtype.Map, field pgconn.FieldDescription, src []byte) (any, error) {
+	if src == nil {
+		return nil, nil
+	}
+
+	oid, format := field.DataTypeOID, field.Format
+	switch oid {
+	case pgtype.BoolOID:
+		var value bool
+		err := typeMap.Scan(oid, format, src, &value)
+		return value, err
+	case pgtype.ByteaOID, pgtype.JSONOID, pgtype.JSONBOID:
+		var value []byte
+		err := typeMap.Scan(oid, format, src, &value)
+		return value, err
+	case pgtype.CIDOID, pgtype.OIDOID, pgtype.XIDOID:
+		var value pgtype.Uint32
+		if err := typeMap.Scan(oid, format, src, &value); err != nil {
+			return nil, err
+		}
+		return value.Value()
+	case pgtype.DateOID:
+		var value pgtype.Date
+		if err := typeMap.Scan(oid, format, src, &value); err != nil {
+			return nil, err
+		}
+		return value.Value()
+	case pgtype.Float4OID:
+		var value float32
+		err := typeMap.Scan(oid, format, src, &value)
+		return float64(value), err
+	case pgtype.Float8OID:
+		var value float64
+		err := typeMap.Scan(oid, format, src, &value)
+		return value, err
+	case pgtype.Int2OID, pgtype.Int4OID, pgtype.Int8OID:
+		var value int64
+		err := typeMap.Scan(oid, format, src, &value)
+		return value, err
+	case pgtype.TimestampOID:
+		var value pgtype.Timestamp
+		if err := typeMap.Scan(oid, format, src, &value); err != nil {
+			return nil, err
+		}
+		return value.Value()
+	case pgtype.TimestamptzOID:
+		var value pgtype.Timestamptz
+		if err := typeMap.Scan(oid, format, src, &value); err != nil {
+			return nil, err
+		}
+		return value.Value()
+	default:
+		var value string
+		err := typeMap.Scan(oid, format, src, &value)
+		return value, err
+	}
+}
@@ -0,0 +1,41 @@
+package repository
+
+import (
+	"fmt"
+	"time"
+)
+
+// Backend is the database driver the queries are run with.
+type Backend string
+
+const (
+	// BackendSQL runs the queries through database/sql with the pgx driver.
+	BackendSQL Backend = "sql"
+	// BackendPgxPool runs the queries on a native pgx connection pool.
+	BackendPgxPool Backend = "pgxpool"
+)
+
+func ParseBackend(value string) (Backend, error) {
+	switch backend := Backend(value); backend {
+	case BackendSQL, BackendPgxPool:
+		return backend, nil
+	default:
+		return "", fmt.Errorf("invalid backend: %s (expected %s or %s)", value, BackendSQL, BackendPgxPool)
+	}
+}
+
+// defaultMaxConns is the connection limit used when PoolConfig has none.
+const defaultMaxConns = 100
+
+// PoolConfig tunes the connection pool of a repository. The zero value of a
+// field keeps the default of the backend. MinConns, HealthCheckPeriod and
+// Dedicated only apply to the pgxpool backend.
+type PoolConfig struct {
+	MaxConns          int
+	MinConns          int
+	MaxConnLifetime   time.Duration
+	HealthCheckPeriod time.Duration
+	// Dedicated runs the queries of every worker on a connection of its own, kept
+	// for the whole run, so that the workers do not wait on the pool.
+	Dedicated bool
+}
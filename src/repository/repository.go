@@ -1,13 +1,28 @@
 package repository
 
 import (
-	"time"
+	"context"
 
 	"github.com/molinama/timescale/src/model"
 )
 
 type Repository interface {
-	RawQuery(params *model.QueryParams) (time.Duration, error)
+	// RawQuery runs the query of the params and reads all of its rows. The query
+	// is canceled when ctx is done.
+	RawQuery(ctx context.Context, params *model.QueryParams) (model.QueryResponse, error)
+}
+
+type workerKey struct{}
+
+// WithWorker returns a context for the queries of the worker, so that they can
+// run on a connection dedicated to the worker.
+func WithWorker(ctx context.Context, worker model.Worker) context.Context {
+	return context.WithValue(ctx, workerKey{}, worker)
+}
+
+func workerFromContext(ctx context.Context) (model.Worker, bool) {
+	worker, ok := ctx.Value(workerKey{}).(model.Worker)
+	return worker, ok
 }
 
 type Config struct {
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"strconv"
+	"time"
+
+	"github.com/molinama/timescale/src/model"
+)
+
+// Separators of the values hashed into the checksum of a result set.
+var (
+	valueSeparator = []byte{0x1f}
+	nullValue      = []byte{0x00}
+	rowSeparator   = []byte{0x1e}
+)
+
+// resultDigest builds the QueryResponse of a result set from its rows, with the
+// times to the first and to the last row since start, the rows, the bytes and a
+// checksum of the ordered rows. The rows are given as the values of the pgx
+// database/sql driver and hashed in a canonical text form, whatever the backend
+// and the protocol format they were read with, so the bytes and checksums of
+// all the backends and modes can be compared.
+type resultDigest struct {
+	start    time.Time
+	response model.QueryResponse
+	checksum hash.Hash
+	value    []byte
+}
+
+func newResultDigest(start time.Time) *resultDigest {
+	return &resultDigest{
+		start:    start,
+		checksum: sha256.New(),
+	}
+}
+
+// firstRow measures the time to the first row, when the next row is the first one.
+func (d *resultDigest) firstRow() {
+	if d.response.Rows == 0 {
+		d.response.FirstRowTime = time.Since(d.start)
+	}
+}
+
+// addRow adds a row with its values, nil for NULL.
+func (d *resultDigest) addRow(values []any) {
+	d.firstRow()
+	d.response.Rows++
+	for _, value := range values {
+		if value == nil {
+			d.checksum.Write(nullValue)
+		} else {
+			d.value = appendCanonical(d.value[:0], value)
+			d.response.Bytes += len(d.value)
+			d.checksum.Write(d.value)
+		}
+		d.checksum.Write(valueSeparator)
+	}
+	d.checksum.Write(rowSeparator)
+}
+
+// appendCanonical appends the text form of a value as database/sql formats it
+// into a sql.RawBytes, except that times are in UTC, so the form does not depend
+// on the time zone of the decoded times.
+func appendCanonical(buf []byte, value any) []byte {
+	switch value := value.(type) {
+	case string:
+		return append(buf, value...)
+	case []byte:
+		return append(buf, value...)
+	case time.Time:
+		return value.UTC().AppendFormat(buf, time.RFC3339Nano)
+	case int64:
+		return strconv.AppendInt(buf, value, 10)
+	case float64:
+		return strconv.AppendFloat(buf, value, 'g', -1, 64)
+	case bool:
+		return strconv.AppendBool(buf, value)
+	default:
+		return fmt.Append(buf, value)
+	}
+}
+
+// finish measures the time to the last row and returns the response. The time
+// to the first row of an empty result is the time to its end.
+func (d *resultDigest) finish() model.QueryResponse {
+	d.response.Checksum = hex.EncodeToString(d.checksum.Sum(nil))
+	d.response.Duration = time.Since(d.start)
+	if d.response.Rows == 0 {
+		d.response.FirstRowTime = d.response.Duration
+	}
+	return d.response
+}
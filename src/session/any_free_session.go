@@ -0,0 +1,39 @@
+package session
+
+import (
+	"sync"
+
+	"github.com/molinama/timescale/src/model"
+	"github.com/molinama/timescale/src/worker"
+)
+
+type anyFreeSession struct {
+	wp         *worker.WorkerPool
+	next       int
+	sessionMux sync.Mutex
+}
+
+// NewAnyFreeSession runs every task on the worker with the fewest pending tasks,
+// no matter its hostname. Ties go to the workers in turn, so an idle pool
+// spreads the tasks over all of its workers.
+func NewAnyFreeSession(wp *worker.WorkerPool) Session {
+	return &anyFreeSession{
+		wp: wp,
+	}
+}
+
+func (as *anyFreeSession) GetWorker(task worker.Task) model.Worker {
+	as.sessionMux.Lock()
+	defer as.sessionMux.Unlock()
+
+	numberWorkers := as.wp.NumberWorkers()
+	free := model.Worker(as.next + 1)
+	for i := 1; i < numberWorkers; i++ {
+		worker := model.Worker((as.next+i)%numberWorkers + 1)
+		if as.wp.Pending(worker) < as.wp.Pending(free) {
+			free = worker
+		}
+	}
+	as.next = int(free) % numberWorkers
+	return free
+}
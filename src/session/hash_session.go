@@ -0,0 +1,64 @@
+package session
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+
+	"github.com/molinama/timescale/src/model"
+	"github.com/molinama/timescale/src/worker"
+)
+
+// virtualNodes is the number of points of every worker on the hash ring, which
+// evens out the share of hostnames of every worker.
+const virtualNodes = 100
+
+type ringPoint struct {
+	hash   uint64
+	worker model.Worker
+}
+
+type hashSession struct {
+	ring []ringPoint
+}
+
+// NewHashSession assigns every hostname to a worker by consistent hashing, so a
+// hostname is always run on the same worker for a given number of workers, and
+// changing the number of workers only moves a share of the hostnames.
+func NewHashSession(wp *worker.WorkerPool) Session {
+	ring := make([]ringPoint, 0, wp.NumberWorkers()*virtualNodes)
+	for i := 1; i <= wp.NumberWorkers(); i++ {
+		for node := 0; node < virtualNodes; node++ {
+			ring = append(ring, ringPoint{
+				hash:   hash("worker-" + strconv.Itoa(i) + "-" + strconv.Itoa(node)),
+				worker: model.Worker(i),
+			})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool { return ring[i].hash < ring[j].hash })
+
+	return &hashSession{ring: ring}
+}
+
+func (hs *hashSession) GetWorker(task worker.Task) model.Worker {
+	hostnameHash := hash(task.Hostname())
+	i := sort.Search(len(hs.ring), func(i int) bool { return hs.ring[i].hash >= hostnameHash })
+	if i == len(hs.ring) {
+		i = 0
+	}
+	return hs.ring[i].worker
+}
+
+// hash is FNV-1a followed by a mix of its bits, so that similar keys such as
+// host_000001 and host_000002 land far apart on the ring.
+func hash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
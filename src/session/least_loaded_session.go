@@ -0,0 +1,45 @@
+package session
+
+import (
+	"sync"
+
+	"github.com/molinama/timescale/src/model"
+	"github.com/molinama/timescale/src/worker"
+)
+
+type leastLoadedSession struct {
+	wp           *worker.WorkerPool
+	workerByHost map[string]model.Worker
+	tasks        []int
+	sessionMux   sync.Mutex
+}
+
+// NewLeastLoadedSession assigns every new hostname to the worker with the fewest
+// tasks so far, the lowest worker on a tie, and keeps every hostname on its
+// worker. Hostnames with many queries thus share their worker with fewer others.
+func NewLeastLoadedSession(wp *worker.WorkerPool) Session {
+	return &leastLoadedSession{
+		wp:           wp,
+		workerByHost: make(map[string]model.Worker),
+		tasks:        make([]int, wp.NumberWorkers()),
+	}
+}
+
+func (ls *leastLoadedSession) GetWorker(task worker.Task) model.Worker {
+	ls.sessionMux.Lock()
+	defer ls.sessionMux.Unlock()
+
+	worker, ok := ls.workerByHost[task.Hostname()]
+	if !ok {
+		least := 0
+		for i := range ls.tasks {
+			if ls.tasks[i] < ls.tasks[least] {
+				least = i
+			}
+		}
+		worker = model.Worker(least + 1)
+		ls.workerByHost[task.Hostname()] = worker
+	}
+	ls.tasks[worker-1]++
+	return worker
+}
@@ -14,6 +14,8 @@ type randomSession struct {
 	sessionMux   sync.Mutex
 }
 
+// NewRandomSession assigns every hostname to a random worker the first time
+// it is seen, and keeps it on that worker.
 func NewRandomSession(wp *worker.WorkerPool) Session {
 	return &randomSession{
 		wp:           wp,
@@ -26,12 +28,7 @@ func (rs *randomSession) GetWorker(task worker.Task) model.Worker {
 	defer rs.sessionMux.Unlock()
 
 	if _, ok := rs.workerByHost[task.Hostname()]; !ok {
-		var workerId int
-		if rs.wp.NumberWorkers() <= 1 {
-			workerId = 1
-		} else {
-			workerId = rand.Intn(rs.wp.NumberWorkers()-1) + 1
-		}
+		workerId := rand.Intn(rs.wp.NumberWorkers()) + 1
 		rs.workerByHost[task.Hostname()] = model.Worker(workerId)
 	}
 	return rs.workerByHost[task.Hostname()]
@@ -0,0 +1,35 @@
+package session
+
+import (
+	"sync"
+
+	"github.com/molinama/timescale/src/model"
+	"github.com/molinama/timescale/src/worker"
+)
+
+type roundRobinSession struct {
+	wp           *worker.WorkerPool
+	workerByHost map[string]model.Worker
+	next         int
+	sessionMux   sync.Mutex
+}
+
+// NewRoundRobinSession assigns the hostnames to the workers in turn, in the
+// order they are first seen, and keeps every hostname on its worker.
+func NewRoundRobinSession(wp *worker.WorkerPool) Session {
+	return &roundRobinSession{
+		wp:           wp,
+		workerByHost: make(map[string]model.Worker),
+	}
+}
+
+func (rs *roundRobinSession) GetWorker(task worker.Task) model.Worker {
+	rs.sessionMux.Lock()
+	defer rs.sessionMux.Unlock()
+
+	if _, ok := rs.workerByHost[task.Hostname()]; !ok {
+		rs.workerByHost[task.Hostname()] = model.Worker(rs.next + 1)
+		rs.next = (rs.next + 1) % rs.wp.NumberWorkers()
+	}
+	return rs.workerByHost[task.Hostname()]
+}
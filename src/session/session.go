@@ -1,6 +1,9 @@
 package session
 
 import (
+	"fmt"
+	"strings"
+
 	"github.com/molinama/timescale/src/model"
 	"github.com/molinama/timescale/src/worker"
 )
@@ -8,3 +11,53 @@ import (
 type Session interface {
 	GetWorker(task worker.Task) model.Worker
 }
+
+// Strategy is how a Session assigns the tasks to the workers.
+type Strategy string
+
+const (
+	// StrategyRandom keeps every hostname on a random worker.
+	StrategyRandom Strategy = "random"
+	// StrategyRoundRobin keeps every hostname on the next worker in turn.
+	StrategyRoundRobin Strategy = "round-robin"
+	// StrategyHash keeps every hostname on the worker of its consistent hash.
+	StrategyHash Strategy = "hash"
+	// StrategyLeastLoaded keeps every hostname on the worker with the fewest tasks so far.
+	StrategyLeastLoaded Strategy = "least-loaded"
+	// StrategyAnyFree runs every task on the worker with the fewest pending tasks.
+	StrategyAnyFree Strategy = "any-free"
+)
+
+var strategies = []Strategy{StrategyRandom, StrategyRoundRobin, StrategyHash, StrategyLeastLoaded, StrategyAnyFree}
+
+// ParseStrategy parses a session strategy name such as "round-robin".
+func ParseStrategy(value string) (Strategy, error) {
+	strategy := Strategy(strings.TrimSpace(value))
+	for _, known := range strategies {
+		if strategy == known {
+			return strategy, nil
+		}
+	}
+
+	names := make([]string, len(strategies))
+	for i, known := range strategies {
+		names[i] = string(known)
+	}
+	return "", fmt.Errorf("invalid session: %s (expected %s)", value, strings.Join(names, ", "))
+}
+
+// NewSession returns a Session of the worker pool that uses the strategy.
+func NewSession(strategy Strategy, wp *worker.WorkerPool) Session {
+	switch strategy {
+	case StrategyRoundRobin:
+		return NewRoundRobinSession(wp)
+	case StrategyHash:
+		return NewHashSession(wp)
+	case StrategyLeastLoaded:
+		return NewLeastLoadedSession(wp)
+	case StrategyAnyFree:
+		return NewAnyFreeSession(wp)
+	default:
+		return NewRandomSession(wp)
+	}
+}
@@ -0,0 +1,134 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/molinama/timescale/src/model"
+	"github.com/molinama/timescale/src/worker"
+	"github.com/stretchr/testify/assert"
+)
+
+type hostTask struct {
+	hostname string
+	release  <-chan struct{}
+	wg       *sync.WaitGroup
+}
+
+func (ht *hostTask) Execute(ctx context.Context, worker model.Worker) {
+	defer ht.wg.Done()
+	<-ht.release
+}
+
+func (ht *hostTask) Hostname() string {
+	return ht.hostname
+}
+
+// distribution assigns tasks of numberHosts hostnames, every hostname tasksPerHost
+// times, and returns the hostnames of every worker. It fails when a hostname is
+// assigned to more than one worker and sticky is set.
+func distribution(t *testing.T, session Session, numberHosts int, tasksPerHost int, sticky bool) map[model.Worker]map[string]int {
+	workers := make(map[model.Worker]map[string]int)
+	workerByHost := make(map[string]model.Worker)
+	for i := 0; i < tasksPerHost; i++ {
+		for host := 0; host < numberHosts; host++ {
+			hostname := fmt.Sprintf("host_%06d", host)
+			worker := session.GetWorker(&hostTask{hostname: hostname})
+			if assigned, ok := workerByHost[hostname]; ok && sticky && assigned != worker {
+				t.Fatalf("%s moved from worker %d to worker %d", hostname, assigned, worker)
+			}
+			workerByHost[hostname] = worker
+			if workers[worker] == nil {
+				workers[worker] = make(map[string]int)
+			}
+			workers[worker][hostname]++
+		}
+	}
+	return workers
+}
+
+func TestSession_Distribution(t *testing.T) {
+	const numberWorkers = 5
+	tests := []struct {
+		strategy Strategy
+		sticky   bool
+		// minHosts is the minimum number of the 1000 hostnames of every worker.
+		minHosts int
+	}{
+		{strategy: StrategyRandom, sticky: true, minHosts: 100},
+		{strategy: StrategyRoundRobin, sticky: true, minHosts: 200},
+		{strategy: StrategyHash, sticky: true, minHosts: 100},
+		{strategy: StrategyLeastLoaded, sticky: true, minHosts: 200},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.strategy), func(t *testing.T) {
+			wp := worker.NewWorkerPool(context.Background(), 1, numberWorkers)
+			workers := distribution(t, NewSession(tt.strategy, wp), 1000, 3, tt.sticky)
+
+			assert.Len(t, workers, numberWorkers)
+			for worker := model.Worker(1); worker <= numberWorkers; worker++ {
+				assert.GreaterOrEqual(t, len(workers[worker]), tt.minHosts, "hostnames of worker %d", worker)
+			}
+		})
+	}
+}
+
+func TestRandomSession_SingleWorker(t *testing.T) {
+	wp := worker.NewWorkerPool(context.Background(), 1, 1)
+	workers := distribution(t, NewRandomSession(wp), 10, 1, true)
+	assert.Len(t, workers[1], 10)
+}
+
+func TestHashSession_Consistent(t *testing.T) {
+	before := distribution(t, NewHashSession(worker.NewWorkerPool(context.Background(), 1, 4)), 1000, 1, true)
+	after := distribution(t, NewHashSession(worker.NewWorkerPool(context.Background(), 1, 5)), 1000, 1, true)
+
+	// Adding a worker only moves the hostnames the new worker takes over.
+	moved := 0
+	for worker, hostnames := range before {
+		for hostname := range hostnames {
+			if _, ok := after[worker][hostname]; !ok {
+				moved++
+			}
+		}
+	}
+	assert.Equal(t, len(after[5]), moved)
+	assert.Less(t, moved, 400)
+}
+
+func TestLeastLoadedSession_Load(t *testing.T) {
+	session := NewLeastLoadedSession(worker.NewWorkerPool(context.Background(), 1, 2))
+
+	// host_a has 3 tasks on worker 1 before host_b and host_c are seen.
+	for i := 0; i < 3; i++ {
+		assert.Equal(t, model.Worker(1), session.GetWorker(&hostTask{hostname: "host_a"}))
+	}
+	assert.Equal(t, model.Worker(2), session.GetWorker(&hostTask{hostname: "host_b"}))
+	assert.Equal(t, model.Worker(2), session.GetWorker(&hostTask{hostname: "host_c"}))
+}
+
+func TestAnyFreeSession(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wp := worker.NewWorkerPool(ctx, 10, 3)
+	wp.Start()
+	session := NewAnyFreeSession(wp)
+
+	// An idle pool gets the tasks in turn, whatever their hostname.
+	var got []model.Worker
+	for i := 0; i < 6; i++ {
+		got = append(got, session.GetWorker(&hostTask{hostname: "host_a"}))
+	}
+	assert.Equal(t, []model.Worker{1, 2, 3, 1, 2, 3}, got)
+
+	// A busy worker is skipped while the others are free.
+	release := make(chan struct{})
+	wp.Add(2, &hostTask{hostname: "host_a", release: release, wg: &wp.WgTasks})
+	for i := 0; i < 4; i++ {
+		assert.NotEqual(t, model.Worker(2), session.GetWorker(&hostTask{hostname: "host_a"}))
+	}
+
+	close(release)
+	wp.Stop(cancel)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+
+	inputparser "github.com/molinama/timescale/src/input_parser"
+	"github.com/molinama/timescale/src/logging"
+	"github.com/molinama/timescale/src/model"
+)
+
+// sweep runs the workload once for every worker count of the config, each time
+// on a new worker pool sharing the repository, and writes the throughput and the
+// query times of every step. An interrupted step is the last one. The options
+// that write a file of a single run, -interval-file and -record-expected, are
+// rejected as every step would overwrite it.
+func sweep(config Config) error {
+	if config.seriesFile != "" {
+		return fmt.Errorf("-interval-file is not supported by sweep, the time series of every step is in its JSON stats")
+	}
+	if config.recordFile != "" {
+		return fmt.Errorf("-record-expected is not supported by sweep, record the expected results with a single run")
+	}
+
+	initLogging()
+
+	repository, err := initRepository(config)
+	if err != nil {
+		return err
+	}
+	if closer, ok := repository.(io.Closer); ok {
+		defer closer.Close()
+	}
+
+	// Standard input can only be read once, so it is kept for every step.
+	var input []byte
+	if config.csvFilePath == STDIN {
+		input, err = io.ReadAll(os.Stdin)
+		if err != nil {
+			return fmt.Errorf("cannot read CSV input: %w", err)
+		}
+	}
+
+	config.percentiles = model.SweepPercentiles(config.percentiles)
+	result := model.Sweep{}
+	for _, workers := range config.sweep {
+		logging.SugaredLog.Infof("Sweep step with %d workers", workers)
+		stepConfig := config
+		stepConfig.numberWorkers = workers
+
+		var reader inputparser.Reader
+		if input != nil {
+			reader = inputparser.NewCSVStreamReader(bytes.NewReader(input))
+		} else {
+			reader, err = initCsvReader(stepConfig)
+			if err != nil {
+				return err
+			}
+		}
+		stats, err := benchmark(stepConfig, repository, reader)
+		reader.Close()
+		if err != nil {
+			return err
+		}
+
+		result.Steps = append(result.Steps, model.SweepStep{Workers: workers, Stats: stats})
+		if stats.Partial {
+			break
+		}
+	}
+
+	return writeSweep(config, result)
+}
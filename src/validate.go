@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	inputparser "github.com/molinama/timescale/src/input_parser"
+	"github.com/molinama/timescale/src/model"
+)
+
+// validate reads the whole CSV input without connecting to the database and
+// writes the number of valid rows and the rejected ones to output. It returns an
+// error when any row is rejected.
+func validate(config Config, output io.Writer) error {
+	reader, err := initCsvReader(config)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	validRows := 0
+	rejectedRows := model.RejectedRowStats{}
+	for {
+		_, err := reader.Parse()
+		if err == io.EOF {
+			break
+		}
+		var rowErr *inputparser.RowError
+		if errors.As(err, &rowErr) {
+			rejectedRows.TotalRejectedRows++
+			rejectedRows.RejectedRows = append(rejectedRows.RejectedRows, model.RejectedRow{Line: rowErr.Line, Reason: rowErr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("cannot read CSV input: %w", err)
+		}
+		validRows++
+	}
+
+	fmt.Fprintf(output, "Valid input rows: %d\n%s", validRows, rejectedRows)
+	if rejectedRows.TotalRejectedRows > 0 {
+		return fmt.Errorf("%d invalid input rows", rejectedRows.TotalRejectedRows)
+	}
+	return nil
+}
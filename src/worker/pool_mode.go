@@ -0,0 +1,34 @@
+package worker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// PoolMode is how the tasks added for a worker are queued and run.
+type PoolMode string
+
+const (
+	// PoolModeDedicated runs every task on the worker it was added for, with a
+	// queue per worker. Hostnames kept on a worker by the session never move.
+	PoolModeDedicated PoolMode = "dedicated"
+	// PoolModeShared queues all the tasks in a single queue and runs them on the
+	// first free worker, ignoring the worker they were added for. With the host
+	// affinity a hostname still runs on one worker at a time.
+	PoolModeShared PoolMode = "shared"
+	// PoolModeStealing queues every task for the worker it was added for, and
+	// lets an idle worker take the queued tasks of the busiest worker. With the
+	// host affinity a stolen task waits for its hostname to complete on the
+	// other workers.
+	PoolModeStealing PoolMode = "stealing"
+)
+
+// ParsePoolMode parses a pool mode name such as "stealing".
+func ParsePoolMode(value string) (PoolMode, error) {
+	switch mode := PoolMode(strings.TrimSpace(value)); mode {
+	case PoolModeDedicated, PoolModeShared, PoolModeStealing:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("invalid pool mode: %s (expected %s, %s or %s)", value, PoolModeDedicated, PoolModeShared, PoolModeStealing)
+	}
+}
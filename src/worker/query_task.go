@@ -1,7 +1,10 @@
 package worker
 
 import (
+	"context"
+	"errors"
 	"sync"
+	"time"
 
 	"github.com/molinama/timescale/src/logging"
 	"github.com/molinama/timescale/src/model"
@@ -9,20 +12,34 @@ import (
 )
 
 type QueryTask struct {
-	repository repository.Repository
-	params     *model.QueryParams
-	results    chan<- model.QueryTaskResult
-	errs       chan<- model.QueryTaskErr
-	wg         *sync.WaitGroup
+	repository    repository.Repository
+	params        *model.QueryParams
+	results       chan<- model.QueryTaskResult
+	errs          chan<- model.QueryTaskErr
+	wg            *sync.WaitGroup
+	intendedStart time.Time
+	timeout       time.Duration
+	retryPolicy   RetryPolicy
+	warmup        bool
+
+	expectedResults *model.ExpectedResults
+	recordedResults *model.ExpectedResults
 }
 
 func NewQueryTask(config QueryTaskConfig) *QueryTask {
 	return &QueryTask{
-		repository: config.Repository,
-		params:     config.Params,
-		results:    config.Results,
-		errs:       config.Errs,
-		wg:         &config.WorkerPool.WgTasks,
+		repository:    config.Repository,
+		params:        config.Params,
+		results:       config.Results,
+		errs:          config.Errs,
+		wg:            &config.WorkerPool.WgTasks,
+		intendedStart: config.IntendedStart,
+		timeout:       config.Timeout,
+		retryPolicy:   config.RetryPolicy,
+		warmup:        config.Warmup,
+
+		expectedResults: config.ExpectedResults,
+		recordedResults: config.RecordedResults,
 	}
 }
 
@@ -30,21 +47,54 @@ func (t *QueryTask) Hostname() string {
 	return t.params.Hostname
 }
 
-func (t *QueryTask) Execute(worker model.Worker) {
+// Execute runs the query, retrying it on transient errors as allowed by the
+// retry policy. The service time of a retried query covers all of its attempts.
+func (t *QueryTask) Execute(ctx context.Context, worker model.Worker) {
 	defer t.wg.Done()
+	ctx = repository.WithWorker(ctx, worker)
 
-	duration, err := t.repository.RawQuery(t.params)
+	start := time.Now()
+	attemptStart := start
+	response, err := t.query(ctx)
+	attempts := 1
+	for err != nil && attempts < t.retryPolicy.MaxAttempts && t.retryPolicy.Retryable(err) {
+		logging.SugaredLog.Warnf("Retrying query after error: %v", err)
+		if t.retryPolicy.Wait(ctx, attempts) != nil {
+			break
+		}
+		attemptStart = time.Now()
+		response, err = t.query(ctx)
+		attempts++
+	}
 	//log.Printf("Query executed: %v", t.params.RawQuery())
 	result := model.QueryTaskResult{
-		Worker:   worker,
-		Hostname: t.params.Hostname,
-		Duration: duration,
+		Worker:       worker,
+		Hostname:     t.params.Hostname,
+		Duration:     attemptStart.Sub(start) + response.Duration,
+		ServiceTime:  attemptStart.Sub(start) + response.Duration,
+		FirstRowTime: response.FirstRowTime,
+		Rows:         response.Rows,
+		Bytes:        response.Bytes,
+		Attempts:     attempts,
+		End:          time.Now(),
+		Warmup:       t.warmup,
+	}
+	if err == nil && t.recordedResults != nil {
+		t.recordedResults.Set(*t.params, model.ExpectedResult{Rows: response.Rows, Checksum: response.Checksum})
+	}
+	if err == nil && t.expectedResults != nil {
+		result.Verified, err = t.expectedResults.Verify(t.params, response)
+	}
+	if !t.intendedStart.IsZero() {
+		result.QueueingDelay = max(start.Sub(t.intendedStart), 0)
+		result.Duration = result.QueueingDelay + result.ServiceTime
 	}
 
 	if err != nil {
 		queryTaskErr := model.QueryTaskErr{
 			QueryTaskResult: result,
 			RawQuery:        t.params.RawQuery(),
+			Time:            result.End,
 			Err:             err,
 		}
 		t.errs <- queryTaskErr
@@ -54,3 +104,18 @@ func (t *QueryTask) Execute(worker model.Worker) {
 	}
 
 }
+
+// query runs a single attempt of the query within the query timeout.
+func (t *QueryTask) query(ctx context.Context) (model.QueryResponse, error) {
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+
+	response, err := t.repository.RawQuery(ctx, t.params)
+	if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = &model.TimeoutError{Timeout: t.timeout, Err: err}
+	}
+	return response, err
+}
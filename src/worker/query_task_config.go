@@ -1,6 +1,8 @@
 package worker
 
 import (
+	"time"
+
 	"github.com/molinama/timescale/src/model"
 	"github.com/molinama/timescale/src/repository"
 )
@@ -11,4 +13,17 @@ type QueryTaskConfig struct {
 	Results    chan<- model.QueryTaskResult
 	Errs       chan<- model.QueryTaskErr
 	WorkerPool *WorkerPool
+	// IntendedStart is the time the task should start in an open-loop run.
+	// It is zero in a closed-loop run.
+	IntendedStart time.Time
+	// Timeout is the maximum time a query can run, or 0 for no limit.
+	Timeout time.Duration
+	// RetryPolicy decides which failed queries are run again.
+	RetryPolicy RetryPolicy
+	// Warmup tags the task results as part of the warm-up.
+	Warmup bool
+	// ExpectedResults, when set, are verified against the query result.
+	ExpectedResults *model.ExpectedResults
+	// RecordedResults, when set, record the query result as the expected one.
+	RecordedResults *model.ExpectedResults
 }
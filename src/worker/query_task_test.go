@@ -0,0 +1,142 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/molinama/timescale/src/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// slowRepository answers after delay unless the query is canceled first.
+type slowRepository struct {
+	delay time.Duration
+}
+
+func (r *slowRepository) RawQuery(ctx context.Context, params *model.QueryParams) (model.QueryResponse, error) {
+	select {
+	case <-time.After(r.delay):
+		return model.QueryResponse{Duration: r.delay}, nil
+	case <-ctx.Done():
+		return model.QueryResponse{}, ctx.Err()
+	}
+}
+
+// flakyRepository fails its first queries with err, as many as failures.
+type flakyRepository struct {
+	failures int
+	err      error
+}
+
+func (r *flakyRepository) RawQuery(ctx context.Context, params *model.QueryParams) (model.QueryResponse, error) {
+	if r.failures > 0 {
+		r.failures--
+		return model.QueryResponse{Duration: time.Millisecond}, r.err
+	}
+	return model.QueryResponse{Duration: time.Millisecond, Rows: 1}, nil
+}
+
+func TestQueryTask_ExecuteRetry(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond, Classes: DefaultRetryClasses}
+	tests := []struct {
+		name         string
+		repository   *flakyRepository
+		wantAttempts int
+		wantErr      bool
+	}{
+		{
+			name:         "First Try",
+			repository:   &flakyRepository{},
+			wantAttempts: 1,
+		},
+		{
+			name:         "Retried",
+			repository:   &flakyRepository{failures: 2, err: &pgconn.PgError{Code: "53300"}},
+			wantAttempts: 3,
+		},
+		{
+			name:         "Attempts Exhausted",
+			repository:   &flakyRepository{failures: 3, err: &pgconn.PgError{Code: "53300"}},
+			wantAttempts: 3,
+			wantErr:      true,
+		},
+		{
+			name:         "Not Retryable",
+			repository:   &flakyRepository{failures: 1, err: &pgconn.PgError{Code: "42601"}},
+			wantAttempts: 1,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			results := make(chan model.QueryTaskResult, 1)
+			errs := make(chan model.QueryTaskErr, 1)
+			task := NewQueryTask(QueryTaskConfig{
+				Repository:  tt.repository,
+				Params:      &model.QueryParams{Hostname: "host_000001"},
+				Results:     results,
+				Errs:        errs,
+				WorkerPool:  &WorkerPool{},
+				RetryPolicy: policy,
+			})
+			task.wg.Add(1)
+			task.Execute(context.Background(), 1)
+
+			var result model.QueryTaskResult
+			if tt.wantErr {
+				result = (<-errs).QueryTaskResult
+			} else {
+				result = <-results
+			}
+			assert.Equal(t, tt.wantAttempts, result.Attempts)
+			assert.GreaterOrEqual(t, result.ServiceTime, time.Duration(tt.wantAttempts)*time.Millisecond)
+		})
+	}
+}
+
+func TestQueryTask_ExecuteTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		ctx         func() context.Context
+		timeout     time.Duration
+		wantTimeout bool
+	}{
+		{
+			name:        "Timeout",
+			ctx:         context.Background,
+			timeout:     time.Millisecond,
+			wantTimeout: true,
+		},
+		{
+			name: "Canceled",
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				return ctx
+			},
+			timeout: time.Minute,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			errs := make(chan model.QueryTaskErr, 1)
+			task := NewQueryTask(QueryTaskConfig{
+				Repository: &slowRepository{delay: time.Minute},
+				Params:     &model.QueryParams{Hostname: "host_000001"},
+				Errs:       errs,
+				WorkerPool: &WorkerPool{},
+				Timeout:    tt.timeout,
+			})
+			task.wg.Add(1)
+			task.Execute(tt.ctx(), 1)
+
+			queryTaskErr := <-errs
+			var timeoutErr *model.TimeoutError
+			assert.Equal(t, tt.wantTimeout, errors.As(queryTaskErr.Err, &timeoutErr))
+			assert.Equal(t, model.Worker(1), queryTaskErr.Worker)
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package worker
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RateScheduler hands out the intended start times of an open-loop run, where
+// tasks are started at a constant rate no matter how fast they complete.
+type RateScheduler struct {
+	rate  float64
+	start time.Time
+	count int64
+}
+
+// NewRateScheduler returns a scheduler for rate tasks per second.
+func NewRateScheduler(rate float64) *RateScheduler {
+	return &RateScheduler{
+		rate: rate,
+	}
+}
+
+// Next waits until the intended start time of the next task and returns it.
+// When the caller falls behind, Next returns immediately with an intended
+// start time in the past, so the delay is accounted in the task latency.
+// Next returns the context error if ctx is done while waiting.
+func (s *RateScheduler) Next(ctx context.Context) (time.Time, error) {
+	if s.count == 0 {
+		s.start = time.Now()
+	}
+	intendedStart := s.start.Add(time.Duration(float64(s.count) * float64(time.Second) / s.rate))
+	s.count++
+
+	wait := time.Until(intendedStart)
+	if wait <= 0 {
+		return intendedStart, nil
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return intendedStart, nil
+	case <-ctx.Done():
+		return intendedStart, ctx.Err()
+	}
+}
+
+// ParseRate parses a rate of tasks per second such as "500/s" or "500".
+func ParseRate(value string) (float64, error) {
+	number := strings.TrimSuffix(strings.TrimSpace(value), "/s")
+	rate, err := strconv.ParseFloat(number, 64)
+	if err != nil || math.IsNaN(rate) || math.IsInf(rate, 0) || rate <= 0 {
+		return 0, fmt.Errorf("invalid rate: %s (expected a positive number of queries per second such as 500/s)", value)
+	}
+	return rate, nil
+}
@@ -0,0 +1,76 @@
+package worker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateScheduler_Next(t *testing.T) {
+	scheduler := NewRateScheduler(100)
+
+	first, _ := scheduler.Next(context.Background())
+	var last time.Time
+	for i := 1; i <= 10; i++ {
+		var err error
+		last, err = scheduler.Next(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(i)*10*time.Millisecond, last.Sub(first))
+	}
+	assert.False(t, time.Now().Before(last), "Next returned before the intended start time")
+}
+
+func TestRateScheduler_NextBehind(t *testing.T) {
+	scheduler := NewRateScheduler(1000)
+
+	first, _ := scheduler.Next(context.Background())
+	time.Sleep(20 * time.Millisecond)
+
+	// The scheduler does not wait for tasks that should have already started.
+	second, _ := scheduler.Next(context.Background())
+	assert.Equal(t, time.Millisecond, second.Sub(first))
+	assert.Greater(t, time.Since(second), 15*time.Millisecond)
+}
+
+func TestRateScheduler_NextCanceled(t *testing.T) {
+	scheduler := NewRateScheduler(0.1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := scheduler.Next(ctx)
+	assert.NoError(t, err)
+
+	start := time.Now()
+	_, err = scheduler.Next(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{value: "500/s", want: 500},
+		{value: "0.5", want: 0.5},
+		{value: "0/s", wantErr: true},
+		{value: "fast", wantErr: true},
+		{value: "NaN", wantErr: true},
+		{value: "Inf/s", wantErr: true},
+		{value: "-Inf", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := ParseRate(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/molinama/timescale/src/model"
+)
+
+// DefaultRetryClasses are the SQLSTATE classes of transient errors: connection
+// exceptions (08), transaction rollbacks such as serialization failures and
+// deadlocks (40), insufficient resources such as too many connections (53) and
+// operator intervention such as a server shutdown (57).
+var DefaultRetryClasses = []string{"08", "40", "53", "57"}
+
+// connectionClass is the SQLSTATE class of connection exceptions, which also
+// covers the connection errors reported without a SQLSTATE.
+const connectionClass = "08"
+
+// RetryPolicy decides which failed queries are run again and how long to wait
+// before every attempt. The wait starts at Backoff and doubles on every retry up
+// to MaxBackoff.
+type RetryPolicy struct {
+	MaxAttempts int
+	Backoff     time.Duration
+	MaxBackoff  time.Duration
+	Classes     []string
+}
+
+// ParseRetryClasses parses comma separated SQLSTATE classes such as "08,53".
+func ParseRetryClasses(value string) ([]string, error) {
+	var classes []string
+	for _, field := range strings.Split(value, ",") {
+		class := strings.ToUpper(strings.TrimSpace(field))
+		if class == "" {
+			continue
+		}
+		if len(class) != 2 {
+			return nil, fmt.Errorf("invalid SQLSTATE class: %s (expected two characters such as 08)", field)
+		}
+		classes = append(classes, class)
+	}
+	return classes, nil
+}
+
+// Retryable reports whether the query error is transient and worth another
+// attempt. Timeouts, cancellations and wrong results are never retried.
+func (p RetryPolicy) Retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return p.retryableClass(pgErr.Code)
+	}
+	return model.IsConnectionError(err) && p.retryableClass(connectionClass)
+}
+
+// Wait waits before the given retry, starting at 1. It returns the context error
+// if ctx is done first.
+func (p RetryPolicy) Wait(ctx context.Context, retry int) error {
+	timer := time.NewTimer(p.backoff(retry))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	backoff := p.Backoff
+	for i := 1; i < retry; i++ {
+		backoff *= 2
+		if p.MaxBackoff > 0 && backoff >= p.MaxBackoff {
+			return p.MaxBackoff
+		}
+	}
+	if p.MaxBackoff > 0 && backoff > p.MaxBackoff {
+		return p.MaxBackoff
+	}
+	return backoff
+}
+
+func (p RetryPolicy) retryableClass(code string) bool {
+	for _, class := range p.Classes {
+		if strings.HasPrefix(code, class) {
+			return true
+		}
+	}
+	return false
+}
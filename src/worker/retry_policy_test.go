@@ -0,0 +1,65 @@
+package worker
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/molinama/timescale/src/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy_Retryable(t *testing.T) {
+	policy := RetryPolicy{MaxAttempts: 3, Classes: DefaultRetryClasses}
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "Too Many Connections", err: &pgconn.PgError{Code: "53300"}, want: true},
+		{name: "Serialization Failure", err: fmt.Errorf("query: %w", &pgconn.PgError{Code: "40001"}), want: true},
+		{name: "Syntax Error", err: &pgconn.PgError{Code: "42601"}},
+		{name: "Bad Connection", err: driver.ErrBadConn, want: true},
+		{name: "Timeout", err: &model.TimeoutError{Timeout: time.Second, Err: context.DeadlineExceeded}},
+		{name: "Canceled", err: context.Canceled},
+		{name: "Mismatch", err: &model.VerificationError{}},
+		{name: "Other", err: errors.New("no such function: time_bucket")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, policy.Retryable(tt.err))
+		})
+	}
+
+	policy.Classes = []string{"53"}
+	assert.False(t, policy.Retryable(driver.ErrBadConn))
+}
+
+func TestRetryPolicy_backoff(t *testing.T) {
+	policy := RetryPolicy{Backoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	var got []time.Duration
+	for retry := 1; retry <= 6; retry++ {
+		got = append(got, policy.backoff(retry))
+	}
+	assert.Equal(t, []time.Duration{
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}, got)
+}
+
+func TestParseRetryClasses(t *testing.T) {
+	classes, err := ParseRetryClasses("08, 53,hv")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"08", "53", "HV"}, classes)
+
+	_, err = ParseRetryClasses("08,53300")
+	assert.Error(t, err)
+}
@@ -1,8 +1,13 @@
 package worker
 
-import "github.com/molinama/timescale/src/model"
+import (
+	"context"
+
+	"github.com/molinama/timescale/src/model"
+)
 
 type Task interface {
-	Execute(worker model.Worker)
+	// Execute runs the task on the worker. The task should stop when ctx is done.
+	Execute(ctx context.Context, worker model.Worker)
 	Hostname() string
 }
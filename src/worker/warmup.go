@@ -0,0 +1,59 @@
+package worker
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Warmup tags the first tasks of a run, by number of tasks or by time, so
+// their results can be excluded from the stats.
+type Warmup struct {
+	tasks    int
+	duration time.Duration
+	start    time.Time
+	end      time.Time
+	count    int
+}
+
+// ParseWarmup parses a warm-up given as a number of tasks such as "500" or as
+// a duration such as "30s".
+func ParseWarmup(value string) (Warmup, error) {
+	value = strings.TrimSpace(value)
+	if tasks, err := strconv.Atoi(value); err == nil && tasks >= 0 {
+		return Warmup{tasks: tasks}, nil
+	}
+	if duration, err := time.ParseDuration(value); err == nil && duration >= 0 {
+		return Warmup{duration: duration}, nil
+	}
+	return Warmup{}, fmt.Errorf("invalid warmup: %s (expected a number of queries such as 500 or a duration such as 30s)", value)
+}
+
+// Enabled reports whether there is any warm-up.
+func (w *Warmup) Enabled() bool {
+	return w.tasks > 0 || w.duration > 0
+}
+
+// Next reports whether the next task is part of the warm-up.
+func (w *Warmup) Next() bool {
+	if w.count == 0 {
+		w.start = time.Now()
+	}
+	w.count++
+
+	warmup := w.count <= w.tasks
+	if w.duration > 0 {
+		warmup = time.Since(w.start) < w.duration
+	}
+	if !warmup && w.end.IsZero() {
+		w.end = time.Now()
+	}
+	return warmup
+}
+
+// End returns when the first task after the warm-up was tagged, or the zero
+// time while the warm-up is not over.
+func (w *Warmup) End() time.Time {
+	return w.end
+}
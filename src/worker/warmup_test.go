@@ -0,0 +1,53 @@
+package worker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWarmup_NextTasks(t *testing.T) {
+	warmup, err := ParseWarmup("3")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	var got []bool
+	for i := 0; i < 5; i++ {
+		got = append(got, warmup.Next())
+		if i == 2 {
+			assert.True(t, warmup.End().IsZero())
+		}
+	}
+	assert.Equal(t, []bool{true, true, true, false, false}, got)
+	assert.False(t, warmup.End().IsZero())
+}
+
+func TestWarmup_NextDuration(t *testing.T) {
+	warmup, err := ParseWarmup("20ms")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.True(t, warmup.Next())
+	assert.True(t, warmup.Next())
+	time.Sleep(30 * time.Millisecond)
+	assert.False(t, warmup.Next())
+}
+
+func TestParseWarmup(t *testing.T) {
+	for _, value := range []string{"500", "30s", "1m30s"} {
+		warmup, err := ParseWarmup(value)
+		assert.NoError(t, err, value)
+		assert.True(t, warmup.Enabled(), value)
+	}
+	warmup, err := ParseWarmup("0")
+	assert.NoError(t, err)
+	assert.False(t, warmup.Enabled())
+
+	for _, value := range []string{"-1", "soon", "30x"} {
+		_, err := ParseWarmup(value)
+		assert.Error(t, err, value)
+	}
+}
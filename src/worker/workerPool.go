@@ -3,84 +3,246 @@ package worker
 import (
 	"context"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/molinama/timescale/src/logging"
 	"github.com/molinama/timescale/src/model"
 	"go.uber.org/zap"
 )
 
+// queuedTask is a task with the worker it was added for.
+type queuedTask struct {
+	task   Task
+	worker model.Worker
+}
+
+// workerMetrics measures the tasks run by a worker.
+type workerMetrics struct {
+	tasks  atomic.Int64
+	stolen atomic.Int64
+	busy   atomic.Int64
+}
+
 type WorkerPool struct {
 	numberWorkers     int
 	numberTasks       int
-	workerChannelsMap map[model.Worker]chan Task
-	stop              bool
+	mode              PoolMode
+	hostAffinity      bool
+	running           map[string]chan struct{}
+	runningMu         sync.Mutex
+	workerChannelsMap map[model.Worker]chan queuedTask
+	work              chan struct{}
+	pending           []atomic.Int64
+	metrics           []workerMetrics
+	started           time.Time
+	stopped           time.Time
 	WgTasks           sync.WaitGroup
 	WgWorkers         sync.WaitGroup
 	mu                sync.RWMutex
 	ctx               context.Context
+	queryCtx          context.Context
+	cancelQueries     context.CancelFunc
 }
 
 func NewWorkerPool(ctx context.Context, numberTasks int, numberWorkers int) *WorkerPool {
+	return NewWorkerPoolWithMode(ctx, numberTasks, numberWorkers, PoolModeDedicated)
+}
+
+// NewWorkerPoolWithMode returns a pool that queues and runs the tasks as set by
+// the mode. numberTasks is the queue size of every worker.
+func NewWorkerPoolWithMode(ctx context.Context, numberTasks int, numberWorkers int, mode PoolMode) *WorkerPool {
+	// Running tasks outlive the pool context so that they can complete on a
+	// shutdown, they are only canceled by CancelQueries.
+	queryCtx, cancelQueries := context.WithCancel(context.WithoutCancel(ctx))
 	return &WorkerPool{
 		numberTasks:       numberTasks,
 		numberWorkers:     numberWorkers,
-		workerChannelsMap: make(map[model.Worker]chan Task, numberWorkers),
+		mode:              mode,
+		running:           make(map[string]chan struct{}),
+		workerChannelsMap: make(map[model.Worker]chan queuedTask, numberWorkers),
+		work:              make(chan struct{}, numberWorkers),
+		pending:           make([]atomic.Int64, numberWorkers),
+		metrics:           make([]workerMetrics, numberWorkers),
 		WgTasks:           sync.WaitGroup{},
 		WgWorkers:         sync.WaitGroup{},
 		ctx:               ctx,
+		queryCtx:          queryCtx,
+		cancelQueries:     cancelQueries,
 	}
 }
 
+// SetHostAffinity sets whether a hostname runs on one worker at a time in the
+// shared and stealing modes, as it does in the dedicated mode. A worker that
+// takes a task of a hostname running on another worker waits for it to
+// complete. It must be called before Start.
+func (wp *WorkerPool) SetHostAffinity(hostAffinity bool) {
+	wp.hostAffinity = hostAffinity
+}
+
 func (wp *WorkerPool) NumberWorkers() int {
 	return wp.numberWorkers
 }
 
+// Pending returns the number of tasks queued or running on the worker.
+func (wp *WorkerPool) Pending(worker model.Worker) int {
+	if worker < 1 || int(worker) > len(wp.pending) {
+		return 0
+	}
+	return int(wp.pending[worker-1].Load())
+}
+
 func (wp *WorkerPool) Start() {
+	wp.mu.Lock()
+	wp.started = time.Now()
+	var sharedChannel chan queuedTask
+	if wp.mode == PoolModeShared {
+		sharedChannel = make(chan queuedTask, wp.numberTasks*wp.numberWorkers)
+	}
 	for i := 1; i <= wp.numberWorkers; i++ {
-		worker := model.Worker(i)
-		wp.WgWorkers.Add(1)
-		workerChannel := make(chan Task, wp.numberTasks)
-
-		wp.mu.Lock()
-		wp.workerChannelsMap[worker] = workerChannel
-		wp.mu.Unlock()
+		workerChannel := sharedChannel
+		if workerChannel == nil {
+			workerChannel = make(chan queuedTask, wp.numberTasks)
+		}
+		wp.workerChannelsMap[model.Worker(i)] = workerChannel
+	}
+	wp.mu.Unlock()
 
-		go wp.run(worker)
+	for i := 1; i <= wp.numberWorkers; i++ {
+		wp.WgWorkers.Add(1)
+		go wp.run(model.Worker(i))
 	}
 }
 
 func (wp *WorkerPool) run(worker model.Worker) {
 	logging.Log.Debug("Start Worker", zap.Int("workerId", int(worker)))
+	defer wp.WgWorkers.Done()
 
 	for {
-		wp.mu.RLock()
-		workerChannel := wp.workerChannelsMap[worker]
-		wp.mu.RUnlock()
+		// Do not start queued tasks once the pool is canceled.
+		if wp.ctx.Err() != nil {
+			logging.Log.Debug("Quitting Worker", zap.Int("workerId", int(worker)))
+			return
+		}
 
-		select {
-		case task, ok := <-workerChannel:
-			if !ok {
-				logging.Log.Debug("Channel is closed for Worker", zap.Int("workerId", int(worker)))
-				wp.WgWorkers.Done()
-				return
+		queued, ok := wp.next(worker)
+		if !ok {
+			logging.Log.Debug("Quitting Worker", zap.Int("workerId", int(worker)))
+			return
+		}
+		if wp.hostAffinity && wp.mode != PoolModeDedicated && !wp.claim(queued.task.Hostname()) {
+			logging.Log.Debug("Quitting Worker", zap.Int("workerId", int(worker)))
+			return
+		}
+		logging.Log.Debug("Worker running Hostname", zap.Int("workerId", int(worker)), zap.String("hostname", queued.task.Hostname()))
+
+		start := time.Now()
+		queued.task.Execute(wp.queryCtx, worker)
+		if wp.hostAffinity && wp.mode != PoolModeDedicated {
+			wp.release(queued.task.Hostname())
+		}
+		metrics := &wp.metrics[worker-1]
+		metrics.busy.Add(int64(time.Since(start)))
+		metrics.tasks.Add(1)
+		wp.pending[queued.worker-1].Add(-1)
+	}
+}
+
+// next waits for the next task of the worker. In the stealing mode an idle
+// worker takes a task of the busiest worker instead of waiting.
+func (wp *WorkerPool) next(worker model.Worker) (queuedTask, bool) {
+	wp.mu.RLock()
+	workerChannel := wp.workerChannelsMap[worker]
+	wp.mu.RUnlock()
+
+	for {
+		if wp.mode == PoolModeStealing {
+			select {
+			case queued, ok := <-workerChannel:
+				return queued, ok
+			default:
 			}
-			logging.Log.Debug("Worker running Hostname", zap.Int("workerId", int(worker)), zap.String("hostname", task.Hostname()))
-			task.Execute(worker)
+			if queued, ok := wp.steal(worker); ok {
+				return queued, true
+			}
+		}
 
+		select {
+		case queued, ok := <-workerChannel:
+			return queued, ok
+		case <-wp.work:
+			// A task was added, maybe to the queue of a busy worker.
 		case <-wp.ctx.Done():
-			logging.Log.Debug("Quitting Worker", zap.Int("workerId", int(worker)))
-			wp.WgWorkers.Done()
-			close(wp.workerChannelsMap[worker])
-			return
+			return queuedTask{}, false
+		}
+	}
+}
+
+// steal takes a queued task of the worker with the longest queue.
+func (wp *WorkerPool) steal(worker model.Worker) (queuedTask, bool) {
+	wp.mu.RLock()
+	var victim model.Worker
+	var victimChannel chan queuedTask
+	for other, otherChannel := range wp.workerChannelsMap {
+		if other != worker && len(otherChannel) > 0 && (victimChannel == nil || len(otherChannel) > len(victimChannel)) {
+			victim, victimChannel = other, otherChannel
 		}
+	}
+	wp.mu.RUnlock()
+	if victimChannel == nil {
+		return queuedTask{}, false
+	}
 
+	select {
+	case queued, ok := <-victimChannel:
+		if !ok {
+			return queuedTask{}, false
+		}
+		// The task is now pending on the worker that runs it.
+		wp.pending[victim-1].Add(-1)
+		wp.pending[worker-1].Add(1)
+		queued.worker = worker
+		wp.metrics[worker-1].stolen.Add(1)
+		return queued, true
+	default:
+		return queuedTask{}, false
 	}
 }
 
-func (wp *WorkerPool) Add(worker model.Worker, task Task) {
-	if !wp.stop {
-		wp.WgTasks.Add(1)
+// claim waits until the hostname is not running on another worker and marks it
+// as running. It returns false if the pool is canceled first.
+func (wp *WorkerPool) claim(hostname string) bool {
+	for {
+		wp.runningMu.Lock()
+		done, running := wp.running[hostname]
+		if !running {
+			wp.running[hostname] = make(chan struct{})
+			wp.runningMu.Unlock()
+			return true
+		}
+		wp.runningMu.Unlock()
+
+		select {
+		case <-done:
+		case <-wp.ctx.Done():
+			return false
+		}
+	}
+}
+
+// release marks the hostname as no longer running and wakes up the workers
+// waiting for it.
+func (wp *WorkerPool) release(hostname string) {
+	wp.runningMu.Lock()
+	defer wp.runningMu.Unlock()
+	close(wp.running[hostname])
+	delete(wp.running, hostname)
+}
 
+// Add queues the task for the worker. The task is dropped if the pool is canceled
+// before the worker accepts it.
+func (wp *WorkerPool) Add(worker model.Worker, task Task) {
+	if wp.ctx.Err() == nil {
 		wp.mu.RLock()
 		workerChannel, exists := wp.workerChannelsMap[worker]
 		wp.mu.RUnlock()
@@ -90,19 +252,81 @@ func (wp *WorkerPool) Add(worker model.Worker, task Task) {
 			return
 		}
 
-		workerChannel <- task
+		wp.WgTasks.Add(1)
+		wp.pending[worker-1].Add(1)
+		select {
+		case workerChannel <- queuedTask{task: task, worker: worker}:
+			if wp.mode == PoolModeStealing {
+				// Wake up an idle worker, if any, to steal the task.
+				select {
+				case wp.work <- struct{}{}:
+				default:
+				}
+			}
+		case <-wp.ctx.Done():
+			wp.pending[worker-1].Add(-1)
+			wp.WgTasks.Done()
+		}
 	}
 }
 
+// Utilization returns the utilization of every worker since the pool started,
+// up to when it stopped.
+func (wp *WorkerPool) Utilization() []model.WorkerUtilization {
+	wp.mu.RLock()
+	end := wp.stopped
+	if end.IsZero() {
+		end = time.Now()
+	}
+	elapsed := end.Sub(wp.started)
+	wp.mu.RUnlock()
+
+	utilization := make([]model.WorkerUtilization, wp.numberWorkers)
+	for i := range utilization {
+		metrics := &wp.metrics[i]
+		utilization[i] = model.WorkerUtilization{
+			Worker:   model.Worker(i + 1),
+			Tasks:    int(metrics.tasks.Load()),
+			Stolen:   int(metrics.stolen.Load()),
+			BusyTime: time.Duration(metrics.busy.Load()),
+		}
+		if elapsed > 0 {
+			utilization[i].Utilization = min(float64(utilization[i].BusyTime)/float64(elapsed), 1)
+		}
+	}
+	return utilization
+}
+
+// Stop waits for all the queued tasks to complete and stops the workers. If the
+// pool is canceled first, such as on a shutdown signal, Stop only waits for the
+// running tasks and the queued ones are dropped.
 func (wp *WorkerPool) Stop(cancel context.CancelFunc) {
 	// Wait all tasks to be completed.
-	wp.WgTasks.Wait()
-	logging.Log.Info("All Tasks Completed")
+	tasksDone := make(chan struct{})
+	go func() {
+		wp.WgTasks.Wait()
+		close(tasksDone)
+	}()
+	select {
+	case <-tasksDone:
+		logging.Log.Info("All Tasks Completed")
+	case <-wp.ctx.Done():
+		logging.Log.Info("Worker Pool Canceled")
+	}
 	cancel()
 	logging.Log.Info("Stopping Worker Pool")
-	wp.stop = true
 
 	// Wait all workers quit.
 	wp.WgWorkers.Wait()
+	wp.cancelQueries()
+	wp.mu.Lock()
+	wp.stopped = time.Now()
+	wp.mu.Unlock()
 	logging.Log.Info("All Workers Ended")
 }
+
+// CancelQueries cancels the context of the running tasks, such as when the
+// grace period of a shutdown expires.
+func (wp *WorkerPool) CancelQueries() {
+	wp.cancelQueries()
+}
@@ -5,10 +5,12 @@ import (
 	"log"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/molinama/timescale/src/model"
+	"github.com/stretchr/testify/assert"
 )
 
 type MockTask struct {
@@ -17,7 +19,7 @@ type MockTask struct {
 	wg        *sync.WaitGroup
 }
 
-func (mt *MockTask) Execute(worker model.Worker) {
+func (mt *MockTask) Execute(ctx context.Context, worker model.Worker) {
 	start := time.Now()
 	// Simulate variable work time
 	time.Sleep(time.Duration(rand.Intn(10)) * time.Millisecond)
@@ -84,6 +86,187 @@ func TestWorkerPool(t *testing.T) {
 	}
 }
 
+type blockingTask struct {
+	started chan<- struct{}
+	release <-chan struct{}
+	wg      *sync.WaitGroup
+}
+
+func (bt *blockingTask) Execute(ctx context.Context, worker model.Worker) {
+	defer bt.wg.Done()
+	bt.started <- struct{}{}
+	select {
+	case <-bt.release:
+	case <-ctx.Done():
+	}
+}
+func (bt *blockingTask) Hostname() string {
+	return "host"
+}
+
+func TestWorkerPoolCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	wp := NewWorkerPool(ctx, 1, 1)
+	wp.Start()
+
+	started := make(chan struct{}, 3)
+	release := make(chan struct{})
+	added := make(chan struct{})
+	go func() {
+		// The first task runs, the second one is queued and the third one blocks.
+		for i := 0; i < 3; i++ {
+			wp.Add(1, &blockingTask{started: started, release: release, wg: &wp.WgTasks})
+		}
+		close(added)
+	}()
+	<-started
+
+	stopped := make(chan struct{})
+	cancel()
+	go func() {
+		wp.Stop(cancel)
+		close(stopped)
+	}()
+
+	select {
+	case <-added:
+	case <-time.After(time.Second):
+		t.Fatal("Add blocked after the pool was canceled")
+	}
+
+	// The running task is waited for.
+	select {
+	case <-stopped:
+		t.Fatal("Stop returned before the running task completed")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(release)
+
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Stop did not return after the running task completed")
+	}
+	if len(started) != 0 {
+		t.Errorf("Expected queued tasks to be dropped, but %d started", len(started))
+	}
+}
+
+type sleepTask struct {
+	sleep time.Duration
+	wg    *sync.WaitGroup
+}
+
+func (st *sleepTask) Execute(ctx context.Context, worker model.Worker) {
+	defer st.wg.Done()
+	time.Sleep(st.sleep)
+}
+func (st *sleepTask) Hostname() string {
+	return "host"
+}
+
+func TestWorkerPoolModes(t *testing.T) {
+	tests := []struct {
+		mode PoolMode
+		// spread is set when the tasks of a busy worker run on the other workers.
+		spread bool
+		stolen bool
+	}{
+		{mode: PoolModeDedicated},
+		{mode: PoolModeShared, spread: true},
+		{mode: PoolModeStealing, spread: true, stolen: true},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			wp := NewWorkerPoolWithMode(ctx, 20, 4, tt.mode)
+			wp.Start()
+
+			// All the tasks are added for worker 1.
+			for i := 0; i < 20; i++ {
+				wp.Add(1, &sleepTask{sleep: 5 * time.Millisecond, wg: &wp.WgTasks})
+			}
+			wp.Stop(cancel)
+
+			utilization := wp.Utilization()
+			if !assert.Len(t, utilization, 4) {
+				return
+			}
+			tasks, stolen := 0, 0
+			for _, worker := range utilization {
+				tasks += worker.Tasks
+				stolen += worker.Stolen
+				assert.GreaterOrEqual(t, worker.Utilization, 0.0)
+				assert.LessOrEqual(t, worker.Utilization, 1.0)
+			}
+			assert.Equal(t, 20, tasks)
+			assert.Equal(t, tt.spread, utilization[0].Tasks < 20, "tasks of worker 1: %d", utilization[0].Tasks)
+			assert.Equal(t, tt.stolen, stolen > 0)
+			assert.GreaterOrEqual(t, utilization[0].BusyTime, time.Duration(utilization[0].Tasks)*5*time.Millisecond)
+			for worker := model.Worker(1); worker <= 4; worker++ {
+				assert.Equal(t, 0, wp.Pending(worker))
+			}
+		})
+	}
+}
+
+// hostTask tracks how many tasks of its hostname run at the same time.
+type hostTask struct {
+	hostname string
+	running  map[string]*atomic.Int32
+	overlaps *atomic.Int32
+	wg       *sync.WaitGroup
+}
+
+func (ht *hostTask) Execute(ctx context.Context, worker model.Worker) {
+	defer ht.wg.Done()
+	if ht.running[ht.hostname].Add(1) > 1 {
+		ht.overlaps.Add(1)
+	}
+	time.Sleep(time.Millisecond)
+	ht.running[ht.hostname].Add(-1)
+}
+func (ht *hostTask) Hostname() string {
+	return ht.hostname
+}
+
+func TestWorkerPoolHostAffinity(t *testing.T) {
+	for _, mode := range []PoolMode{PoolModeShared, PoolModeStealing} {
+		t.Run(string(mode), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			wp := NewWorkerPoolWithMode(ctx, 20, 4, mode)
+			wp.SetHostAffinity(true)
+			wp.Start()
+
+			hostnames := []string{"host1", "host2"}
+			running := map[string]*atomic.Int32{"host1": {}, "host2": {}}
+			var overlaps atomic.Int32
+			// The tasks of both hostnames are added for worker 1 so that the
+			// other workers take them.
+			for i := 0; i < 40; i++ {
+				wp.Add(1, &hostTask{hostname: hostnames[i%2], running: running, overlaps: &overlaps, wg: &wp.WgTasks})
+			}
+			wp.Stop(cancel)
+
+			tasks := 0
+			for _, worker := range wp.Utilization() {
+				tasks += worker.Tasks
+			}
+			assert.Equal(t, 40, tasks)
+			assert.Equal(t, int32(0), overlaps.Load(), "tasks of a hostname ran on several workers at once")
+		})
+	}
+}
+
+func TestParsePoolMode(t *testing.T) {
+	mode, err := ParsePoolMode(" stealing")
+	assert.NoError(t, err)
+	assert.Equal(t, PoolModeStealing, mode)
+
+	_, err = ParsePoolMode("steal")
+	assert.Error(t, err)
+}
+
 func BenchmarkWorkerPool(b *testing.B) {
 	ctx, cancel := context.WithCancel(context.Background())
 	numberWorkers := 10